@@ -0,0 +1,191 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: analyze.go
+ * Description:
+ *   The analyze command, which reports on an image instead of
+ *   transforming it.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// The size of each color's square in a rendered swatch.
+const SWATCH_SIZE = 128
+
+/*
+ * One dominant color of an image.
+ */
+type paletteEntry struct {
+	Hex        string  `json:"hex"`
+	Coverage   float64 `json:"coverage"`
+	Foreground string  `json:"foreground"`
+	Contrast   float64 `json:"contrast"`
+
+	color color.Color
+}
+
+/*
+ * Count how many pixels are closest to each palette color, in parallel.
+ */
+func paletteCoverage(pixels [][3]uint8, pal color.Palette) []int {
+	rgb, _ := paletteRGB(pal)
+	counts := make([]int, len(pal))
+
+	var mu sync.Mutex
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(0, len(pixels), func(lo int, hi int) {
+		local := make([]int, len(pal))
+		for _, p := range pixels[lo:hi] {
+			local[nearestColor(rgb, float64(p[0]), float64(p[1]), float64(p[2]))]++
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for i, n := range local {
+			counts[i] += n
+		}
+	})
+	return counts
+}
+
+/*
+ * Find the n dominant colors of an image, most common first.
+ */
+func dominantColors(img image.Image, n int, method string) ([]paletteEntry, error) {
+	samples := samplePixels(img, MAX_PALETTE_SAMPLES)
+	if len(samples) == 0 {
+		return nil, errors.New("image has no opaque pixels")
+	}
+
+	var pal color.Palette
+	switch method {
+	case "kmeans":
+		pal, _ = kmeans(samples, n)
+	case "median-cut":
+		pal = medianCut(samples, n)
+	default:
+		return nil, fmt.Errorf("%s is not a valid method", method)
+	}
+
+	counts := paletteCoverage(samples, pal)
+	entries := make([]paletteEntry, 0, len(pal))
+	for i, c := range pal {
+		if counts[i] == 0 {
+			continue
+		}
+		fg := contrastingForeground(c)
+		entries = append(entries, paletteEntry{
+			Hex:        hexColor(c),
+			Coverage:   math.Round(float64(counts[i])*10000/float64(len(samples))) / 100,
+			Foreground: hexColor(fg),
+			Contrast:   math.Round(contrastRatio(c, fg)*100) / 100,
+			color:      c,
+		})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Coverage > entries[j].Coverage
+	})
+	return entries, nil
+}
+
+/*
+ * Render a swatch with a square for each color. The bar along the bottom
+ * of each square is drawn in its foreground color and is as wide as the
+ * color's share of the image.
+ */
+func renderSwatch(entries []paletteEntry) image.Image {
+	swatch := image.NewRGBA(image.Rect(0, 0, SWATCH_SIZE*len(entries), SWATCH_SIZE))
+	barHeight := SWATCH_SIZE / 8
+
+	for i, entry := range entries {
+		x0 := i * SWATCH_SIZE
+		square := image.Rect(x0, 0, x0+SWATCH_SIZE, SWATCH_SIZE)
+		draw.Draw(swatch, square, image.NewUniform(entry.color), image.Point{}, draw.Src)
+
+		fg, _ := parseColor(entry.Foreground)
+		barWidth := int(float64(SWATCH_SIZE) * entry.Coverage / 100)
+		bar := image.Rect(x0, SWATCH_SIZE-barHeight, x0+max(1, barWidth), SWATCH_SIZE)
+		draw.Draw(swatch, bar, image.NewUniform(fg), image.Point{}, draw.Src)
+	}
+	return swatch
+}
+
+/*
+ * Print the dominant colors of an image as JSON, and optionally save
+ * them as a swatch image.
+ */
+func analyzePalette(args []string) error {
+	flags := flag.NewFlagSet("analyze palette", flag.ExitOnError)
+	inputPath := flags.String("i", "", "input image path")
+	numColors := flags.Int("n", 6, "number of colors to extract")
+	method := flags.String("method", "kmeans", "clustering method (kmeans or median-cut)")
+	swatchPath := flags.String("o", "", "optional path to save a swatch image to")
+	flags.Parse(args)
+
+	// The input may also be given after the flags.
+	if *inputPath == "" && flags.NArg() > 0 {
+		*inputPath = flags.Arg(0)
+	}
+	if *inputPath == "" {
+		return errors.New("input path is required")
+	}
+	if *numColors < 1 || *numColors > MAX_PALETTE_SIZE {
+		return fmt.Errorf("-n must be between 1 and %d", MAX_PALETTE_SIZE)
+	}
+
+	img, err := decodeImage(*inputPath)
+	if err != nil {
+		return err
+	}
+
+	entries, err := dominantColors(img, *numColors, *method)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(entries); err != nil {
+		return err
+	}
+
+	if *swatchPath != "" {
+		return saveImage(renderSwatch(entries), *swatchPath)
+	}
+	return nil
+}
+
+/*
+ * Run the analyze command with the arguments that follow it.
+ */
+func runAnalyze(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: imagebeautifier analyze palette -i=[input image path] [-n=6] [-o=swatch.png]")
+	}
+
+	numCpu := runtime.NumCPU()
+	pool = WpNew(numCpu, numCpu)
+	pool.Start()
+	defer pool.WaitAndStop()
+
+	switch args[0] {
+	case "palette":
+		return analyzePalette(args[1:])
+	default:
+		return fmt.Errorf("%s is not a valid analysis", args[0])
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: analyze_test.go
+ * Description:
+ *   Tests for finding the dominant colors of an image.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+)
+
+func TestContrastRatio(t *testing.T) {
+	tests := []struct {
+		c0, c1 color.Color
+		want   float64
+	}{
+		{color.Black, color.White, 21},
+		{color.White, color.Black, 21},
+		{color.White, color.White, 1},
+		{color.RGBA{255, 0, 0, 255}, color.White, 4},
+		{color.RGBA{0, 0, 255, 255}, color.White, 8.59},
+	}
+	for _, tt := range tests {
+		if got := contrastRatio(tt.c0, tt.c1); math.Abs(got-tt.want) > 0.01 {
+			t.Errorf("contrastRatio(%v, %v) = %.3f, want %.2f", tt.c0, tt.c1, got, tt.want)
+		}
+	}
+}
+
+func TestContrastingForeground(t *testing.T) {
+	tests := []struct {
+		bg   color.Color
+		want string
+	}{
+		{color.Black, "#ffffff"},
+		{color.White, "#000000"},
+		{color.RGBA{255, 255, 0, 255}, "#000000"},
+		{color.RGBA{0, 0, 128, 255}, "#ffffff"},
+	}
+	for _, tt := range tests {
+		if got := hexColor(contrastingForeground(tt.bg)); got != tt.want {
+			t.Errorf("contrastingForeground(%v) = %s, want %s", tt.bg, got, tt.want)
+		}
+	}
+}
+
+func TestDominantColors(t *testing.T) {
+	// Three quarters red and one quarter blue.
+	img := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{255, 0, 0, 255}), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, 40, 10), image.NewUniform(color.RGBA{0, 0, 255, 255}), image.Point{}, draw.Src)
+
+	for _, method := range []string{"kmeans", "median-cut"} {
+		entries, err := dominantColors(img, 4, method)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 2 {
+			t.Fatalf("%s found %d colors, want 2", method, len(entries))
+		}
+		want := []struct {
+			hex      string
+			coverage float64
+		}{{"#ff0000", 75}, {"#0000ff", 25}}
+		for i, w := range want {
+			if entries[i].Hex != w.hex || entries[i].Coverage != w.coverage {
+				t.Errorf("%s entry %d = %s at %v%%, want %s at %v%%",
+					method, i, entries[i].Hex, entries[i].Coverage, w.hex, w.coverage)
+			}
+		}
+	}
+}
+
+func TestDominantColorsErrors(t *testing.T) {
+	if _, err := dominantColors(image.NewNRGBA(image.Rect(0, 0, 4, 4)), 4, "kmeans"); err == nil {
+		t.Error("a fully transparent image should have no dominant colors")
+	}
+	if _, err := dominantColors(gradientImage(4, 4), 4, "octree"); err == nil {
+		t.Error("octree should not be a valid method")
+	}
+}
+
+func TestRenderSwatch(t *testing.T) {
+	entries := []paletteEntry{
+		{Hex: "#ff0000", Coverage: 50, Foreground: "#000000", color: color.RGBA{255, 0, 0, 255}},
+		{Hex: "#ffffff", Coverage: 50, Foreground: "#000000", color: color.White},
+	}
+	swatch := renderSwatch(entries)
+	if got := swatch.Bounds().Size(); got != image.Pt(2*SWATCH_SIZE, SWATCH_SIZE) {
+		t.Fatalf("swatch is %v, want %v", got, image.Pt(2*SWATCH_SIZE, SWATCH_SIZE))
+	}
+	if r, g, _, _ := swatch.At(1, 1).RGBA(); r != 0xffff || g != 0 {
+		t.Errorf("the first square is not red")
+	}
+	// The bar covers half of the bottom of each square.
+	bottom := SWATCH_SIZE - 1
+	if r, _, _, _ := swatch.At(SWATCH_SIZE/4, bottom).RGBA(); r != 0 {
+		t.Errorf("the coverage bar is missing")
+	}
+	if r, _, _, _ := swatch.At(SWATCH_SIZE*3/4, bottom).RGBA(); r != 0xffff {
+		t.Errorf("the coverage bar is wider than the coverage")
+	}
+}
@@ -0,0 +1,285 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: artistic.go
+ * Description:
+ *   Artistic filters: oil paint, posterize, cartoon and pencil sketch.
+ */
+
+package main
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"math"
+)
+
+/*
+ * Snap an 8-bit value to the nearest of levels evenly spaced values.
+ */
+func posterizeValue(v uint8, levels int) uint8 {
+	step := 255 / float64(levels-1)
+	return uint8(math.Round(math.Round(float64(v)/step) * step))
+}
+
+/*
+ * Posterize every pixel of an RGBA image in place. Colors are taken out of
+ * premultiplied alpha first, so see-through pixels keep their hue.
+ */
+func posterizeRGBA(img *image.RGBA, levels int) {
+	bounds := img.Bounds()
+
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				i := img.PixOffset(x, y)
+				a := uint32(img.Pix[i+3])
+				if a == 0 {
+					continue
+				}
+				for c := 0; c < 3; c++ {
+					straight := uint8(uint32(img.Pix[i+c]) * 255 / a)
+					img.Pix[i+c] = uint8(uint32(posterizeValue(straight, levels)) * a / 255)
+				}
+			}
+		}
+	})
+}
+
+/*
+ * Get a function that will reduce every channel of any image to the given
+ * number of levels.
+ */
+func PosterizeT(levels int) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		dst := copyRGBA(img)
+		posterizeRGBA(dst, levels)
+		return dst, nil
+	}
+}
+
+/*
+ * Get a function that will make any image look like an oil painting. Every
+ * pixel looks at the pixels around it, sorts them into levels buckets by
+ * brightness, and takes the average color of the fullest bucket.
+ */
+func OilPaintT(radius int, levels int) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		src := toRGBA(img)
+		bounds := src.Bounds()
+		dst := image.NewRGBA(bounds)
+
+		pool := GetGlobalWorkers()
+		pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+			counts := make([]int, levels)
+			sums := make([][4]int, levels)
+
+			for y := lo; y < hi; y++ {
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					for l := range counts {
+						counts[l] = 0
+						sums[l] = [4]int{}
+					}
+
+					for dy := -radius; dy <= radius; dy++ {
+						py := clamp(y+dy, bounds.Min.Y, bounds.Max.Y-1)
+						for dx := -radius; dx <= radius; dx++ {
+							px := clamp(x+dx, bounds.Min.X, bounds.Max.X-1)
+							i := src.PixOffset(px, py)
+							r, g, b, a := int(src.Pix[i]), int(src.Pix[i+1]), int(src.Pix[i+2]), int(src.Pix[i+3])
+
+							l := (r + g + b) / 3 * levels / 256
+							counts[l]++
+							sums[l][0] += r
+							sums[l][1] += g
+							sums[l][2] += b
+							sums[l][3] += a
+						}
+					}
+
+					best := 0
+					for l := range counts {
+						if counts[l] > counts[best] {
+							best = l
+						}
+					}
+					n := counts[best]
+					i := dst.PixOffset(x, y)
+					dst.Pix[i] = uint8(sums[best][0] / n)
+					dst.Pix[i+1] = uint8(sums[best][1] / n)
+					dst.Pix[i+2] = uint8(sums[best][2] / n)
+					dst.Pix[i+3] = uint8(sums[best][3] / n)
+				}
+			}
+		})
+		return dst, nil
+	}
+}
+
+/*
+ * Get a function that will make any image look like a cartoon: flatten
+ * colors with repeated bilateral smoothing and posterizing, then draw dark
+ * lines wherever the Sobel gradient is stronger than edge.
+ */
+func CartoonT(levels int, edge float64, passes int) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		smooth := img
+		for i := 0; i < passes; i++ {
+			var err error
+			smooth, err = BilateralT(3, 40)(smooth)
+			if err != nil {
+				return nil, err
+			}
+		}
+		dst := copyRGBA(smooth)
+		posterizeRGBA(dst, levels)
+
+		// Find edges on a lightly blurred copy so that noise doesn't turn
+		// into lines.
+		field, err := computeGradients(convolveParallel(img, gaussianKernel(1)), "sobel")
+		if err != nil {
+			return nil, err
+		}
+
+		bounds := dst.Bounds()
+		width := bounds.Dx()
+		pool := GetGlobalWorkers()
+		pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+			for y := lo; y < hi; y++ {
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					if field.mag[(y-bounds.Min.Y)*width+x-bounds.Min.X] < edge {
+						continue
+					}
+					i := dst.PixOffset(x, y)
+					dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2] = 0, 0, 0
+				}
+			}
+		})
+		return dst, nil
+	}
+}
+
+/*
+ * Get a function that will turn any image into a pencil sketch: take the
+ * grayscale image, blur its inverse, and color dodge the two together.
+ */
+func SketchT(sigma float64) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		gray := toGray(img)
+		bounds := gray.Bounds()
+		width := bounds.Dx()
+
+		// Blurring the inverse is the same as inverting the blur.
+		blurred := gaussianBlurGray(gray, sigma, gaussianRadius(sigma))
+		sketch := image.NewGray(bounds)
+
+		pool := GetGlobalWorkers()
+		pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+			for y := lo; y < hi; y++ {
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					base := float64(gray.GrayAt(x, y).Y)
+					inverted := 255 - blurred[(y-bounds.Min.Y)*width+x-bounds.Min.X]
+
+					// Color dodge: base / (1 - blend).
+					v := 255.0
+					if inverted < 255 {
+						v = math.Min(255, base*255/(255-inverted))
+					}
+					sketch.SetGray(x, y, color.Gray{uint8(v)})
+				}
+			}
+		})
+		return sketch, nil
+	}
+}
+
+/*
+ * Build a posterize transformation from its command line arguments.
+ */
+func posterizeFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("levels"); err != nil {
+		return nil, err
+	}
+
+	levels, err := args.getInt("levels", 4)
+	if err != nil {
+		return nil, err
+	}
+	if levels < 2 || levels > 256 {
+		return nil, errors.New("levels must be between 2 and 256")
+	}
+	return PosterizeT(levels), nil
+}
+
+/*
+ * Build an oilpaint transformation from its command line arguments.
+ */
+func oilPaintFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("radius", "levels"); err != nil {
+		return nil, err
+	}
+
+	radius, err := args.getInt("radius", 3)
+	if err != nil {
+		return nil, err
+	}
+	levels, err := args.getInt("levels", 20)
+	if err != nil {
+		return nil, err
+	}
+	if radius < 1 {
+		return nil, errors.New("radius must be at least 1")
+	}
+	if levels < 2 || levels > 256 {
+		return nil, errors.New("levels must be between 2 and 256")
+	}
+	return OilPaintT(radius, levels), nil
+}
+
+/*
+ * Build a cartoon transformation from its command line arguments.
+ */
+func cartoonFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("levels", "edge", "passes"); err != nil {
+		return nil, err
+	}
+
+	levels, err := args.getInt("levels", 8)
+	if err != nil {
+		return nil, err
+	}
+	edge, err := args.getFloat("edge", 60)
+	if err != nil {
+		return nil, err
+	}
+	passes, err := args.getInt("passes", 2)
+	if err != nil {
+		return nil, err
+	}
+	if levels < 2 || levels > 256 {
+		return nil, errors.New("levels must be between 2 and 256")
+	}
+	if passes < 0 {
+		return nil, errors.New("passes must not be negative")
+	}
+	return CartoonT(levels, edge, passes), nil
+}
+
+/*
+ * Build a sketch transformation from its command line arguments.
+ */
+func sketchFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("sigma"); err != nil {
+		return nil, err
+	}
+
+	sigma, err := args.getFloat("sigma", 8)
+	if err != nil {
+		return nil, err
+	}
+	if sigma <= 0 {
+		return nil, errors.New("sigma must be greater than 0")
+	}
+	return SketchT(sigma), nil
+}
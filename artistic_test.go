@@ -0,0 +1,143 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: artistic_test.go
+ * Description:
+ *   Tests for the posterize, oil paint, cartoon and sketch filters.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestPosterizeValue(t *testing.T) {
+	tests := []struct {
+		v      uint8
+		levels int
+		want   uint8
+	}{
+		{0, 2, 0},
+		{127, 2, 0},
+		{128, 2, 255},
+		{42, 4, 0},
+		{43, 4, 85},
+		{200, 4, 170},
+		{255, 4, 255},
+		{90, 3, 128},
+		{17, 256, 17},
+	}
+	for _, tt := range tests {
+		if got := posterizeValue(tt.v, tt.levels); got != tt.want {
+			t.Errorf("posterizeValue(%d, %d) = %d, want %d", tt.v, tt.levels, got, tt.want)
+		}
+	}
+}
+
+func TestPosterizeLevelCount(t *testing.T) {
+	out, err := PosterizeT(3)(gradientImage(64, 8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[uint8]bool{}
+	for _, v := range out.(*image.RGBA).Pix {
+		seen[v] = true
+	}
+	for v := range seen {
+		if v != 0 && v != 128 && v != 255 {
+			t.Errorf("posterizing to 3 levels made %d", v)
+		}
+	}
+}
+
+func TestPosterizeKeepsHueOfTranslucentPixels(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.Set(0, 0, color.NRGBA{250, 10, 0, 128})
+	out, err := PosterizeT(2)(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := color.NRGBAModel.Convert(out.At(0, 0)).(color.NRGBA)
+	if got.R < 250 || got.G != 0 || got.B != 0 || got.A != 128 {
+		t.Errorf("posterized pixel is %v, want red at alpha 128", got)
+	}
+}
+
+func TestOilPaint(t *testing.T) {
+	// A flat image stays flat, and a lone speck is painted over.
+	img := flatImage(9, 9, color.RGBA{30, 60, 90, 255})
+	img.SetRGBA(4, 4, color.RGBA{255, 255, 255, 255})
+	out, err := OilPaintT(2, 8)(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := out.(*image.RGBA)
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 9; x++ {
+			if got := dst.RGBAAt(x, y); got != (color.RGBA{30, 60, 90, 255}) {
+				t.Fatalf("pixel at (%d, %d) = %v", x, y, got)
+			}
+		}
+	}
+}
+
+func TestSketch(t *testing.T) {
+	// White paper with a black line stays white around the line.
+	img := flatImage(20, 20, color.White)
+	for y := 0; y < 20; y++ {
+		img.SetRGBA(10, y, color.RGBA{0, 0, 0, 255})
+	}
+	out, err := SketchT(2)(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gray := out.(*image.Gray)
+	if got := gray.GrayAt(0, 10).Y; got != 255 {
+		t.Errorf("the paper became %d", got)
+	}
+	if got := gray.GrayAt(10, 10).Y; got != 0 {
+		t.Errorf("the line became %d", got)
+	}
+}
+
+func TestCartoonDrawsOutlines(t *testing.T) {
+	img := squareImage(image.Pt(30, 30), image.Rect(10, 10, 20, 20))
+	out, err := CartoonT(4, 60, 1)(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := out.(*image.RGBA)
+	if got := dst.RGBAAt(15, 15).R; got != 255 {
+		t.Errorf("the middle of the square became %d", got)
+	}
+	// The outline is drawn in black, so it has to show on the white side.
+	if got := dst.RGBAAt(10, 15).R; got != 0 {
+		t.Errorf("the edge of the square is %d, want an outline", got)
+	}
+}
+
+func TestArtisticArgs(t *testing.T) {
+	tests := []struct {
+		commands string
+		ok       bool
+	}{
+		{"posterize", true},
+		{"posterize,levels=256", true},
+		{"posterize,levels=1", false},
+		{"oilpaint,radius=1,levels=2", true},
+		{"oilpaint,radius=0", false},
+		{"oilpaint,levels=300", false},
+		{"cartoon,levels=6,edge=30,passes=0", true},
+		{"cartoon,passes=-1", false},
+		{"sketch,sigma=3", true},
+		{"sketch,sigma=0", false},
+	}
+	for _, tt := range tests {
+		_, err := TokensToTfms(SplitCommands(tt.commands), 1)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.commands, err, tt.ok)
+		}
+	}
+}
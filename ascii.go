@@ -0,0 +1,127 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: ascii.go
+ * Description:
+ *   Write images out as ASCII art, either as plain text or colored with
+ *   ANSI escape codes.
+ */
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Characters from the least to the most ink. Brighter cells get more ink,
+// since the text is usually read on a dark terminal.
+const ASCII_RAMP = " .:-=+*#%@"
+
+// Terminal characters are about twice as tall as they are wide.
+const ASCII_CELL_ASPECT = 2.0
+
+/*
+ * Check whether an output path asks for ASCII art instead of an image.
+ */
+func isAsciiPath(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".txt" || ext == ".ans"
+}
+
+/*
+ * Average the color of every cell of an image, with cols cells per row.
+ * Rows of cells are split between workers.
+ *
+ * Returns: The average colors, row by row, and the number of rows, or an
+ * error if the image is empty.
+ */
+func asciiCells(img image.Image, cols int) ([]color.RGBA, int, error) {
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return nil, 0, errors.New("ascii: the image is empty")
+	}
+	cols = min(cols, bounds.Dx())
+	cellW := float64(bounds.Dx()) / float64(cols)
+	cellH := cellW * ASCII_CELL_ASPECT
+	// The last row of cells may be cut off by the bottom of the image.
+	rows := int(math.Ceil(float64(bounds.Dy()) / cellH))
+
+	cells := make([]color.RGBA, cols*rows)
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(0, rows, func(lo int, hi int) {
+		for row := lo; row < hi; row++ {
+			y0 := min(bounds.Max.Y-1, bounds.Min.Y+int(float64(row)*cellH))
+			y1 := min(bounds.Max.Y, bounds.Min.Y+int(float64(row+1)*cellH))
+			for col := 0; col < cols; col++ {
+				x0 := bounds.Min.X + int(float64(col)*cellW)
+				x1 := min(bounds.Max.X, bounds.Min.X+int(float64(col+1)*cellW))
+
+				var r, g, b, a, n uint32
+				for y := y0; y < max(y1, y0+1); y++ {
+					for x := x0; x < max(x1, x0+1); x++ {
+						pr, pg, pb, pa := img.At(x, y).RGBA()
+						r += pr >> 8
+						g += pg >> 8
+						b += pb >> 8
+						a += pa >> 8
+						n++
+					}
+				}
+				cells[row*cols+col] = color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), uint8(a / n)}
+			}
+		}
+	})
+	return cells, rows, nil
+}
+
+/*
+ * Save an image as ASCII art that is cols characters wide. Paths ending in
+ * .ans get 24-bit ANSI colors, and any other path gets plain text.
+ */
+func saveAscii(img image.Image, path string, cols int) error {
+	if cols < 1 {
+		return errors.New("ascii: columns must be at least 1")
+	}
+
+	cells, rows, err := asciiCells(img, cols)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	colored := strings.ToLower(filepath.Ext(path)) == ".ans"
+	cols = len(cells) / rows
+	ramp := []rune(ASCII_RAMP)
+
+	out := bufio.NewWriter(file)
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			c := cells[row*cols+col]
+			// Pixels are premultiplied, so transparent cells come out empty.
+			lum := (0.2126*float64(c.R) + 0.7152*float64(c.G) + 0.0722*float64(c.B)) / 255
+			ch := ramp[min(len(ramp)-1, int(lum*float64(len(ramp))))]
+			if colored {
+				fmt.Fprintf(out, "\x1b[38;2;%d;%d;%dm%c", c.R, c.G, c.B, ch)
+			} else {
+				out.WriteRune(ch)
+			}
+		}
+		if colored {
+			out.WriteString("\x1b[0m")
+		}
+		out.WriteString("\n")
+	}
+	return out.Flush()
+}
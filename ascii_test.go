@@ -0,0 +1,125 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: ascii_test.go
+ * Description:
+ *   Tests for writing images out as ASCII art.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsAsciiPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"out.txt", true},
+		{"dir/OUT.ANS", true},
+		{"out.png", false},
+		{"txt", false},
+		{"out.txt.png", false},
+	}
+	for _, tt := range tests {
+		if got := isAsciiPath(tt.path); got != tt.want {
+			t.Errorf("isAsciiPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestAsciiCellsRows(t *testing.T) {
+	tests := []struct {
+		size     image.Point
+		cols     int
+		wantCols int
+		wantRows int
+	}{
+		{image.Pt(10, 8), 10, 10, 4},
+		// The last row only covers the bottom pixel.
+		{image.Pt(10, 7), 10, 10, 4},
+		{image.Pt(10, 1), 10, 10, 1},
+		{image.Pt(40, 30), 10, 10, 4},
+		{image.Pt(4, 9), 100, 4, 5},
+	}
+	for _, tt := range tests {
+		img := image.NewRGBA(image.Rectangle{Min: image.Pt(3, -2), Max: image.Pt(3, -2).Add(tt.size)})
+		cells, rows, err := asciiCells(img, tt.cols)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rows != tt.wantRows || len(cells) != tt.wantCols*tt.wantRows {
+			t.Errorf("%v at %d columns: %d cells in %d rows, want %d columns and %d rows",
+				tt.size, tt.cols, len(cells), rows, tt.wantCols, tt.wantRows)
+		}
+	}
+
+	if _, _, err := asciiCells(image.NewRGBA(image.Rect(0, 0, 0, 5)), 10); err == nil {
+		t.Error("an empty image should be an error")
+	}
+}
+
+func TestAsciiCellsKeepsBottomRow(t *testing.T) {
+	img := flatImage(10, 9, color.Black)
+	for x := 0; x < 10; x++ {
+		img.SetRGBA(x, 8, color.RGBA{255, 255, 255, 255})
+	}
+	cells, rows, err := asciiCells(img, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Cells are 2 by 4 pixels, so the last row only has the white line.
+	if rows != 3 {
+		t.Fatalf("got %d rows, want 3", rows)
+	}
+	if got := cells[10]; got != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("the bottom cell is %v, want white", got)
+	}
+	if got := cells[0]; got != (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("the top cell is %v, want black", got)
+	}
+}
+
+func TestSaveAscii(t *testing.T) {
+	img := flatImage(8, 8, color.White)
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 4; x++ {
+			img.SetRGBA(x, y, color.RGBA{0, 0, 0, 255})
+		}
+	}
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "out.txt")
+	if err := saveAscii(img, path, 4); err != nil {
+		t.Fatal(err)
+	}
+	text, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(text), "  @@\n  @@\n"; got != want {
+		t.Errorf("plain text is %q, want %q", got, want)
+	}
+
+	path = filepath.Join(dir, "out.ans")
+	if err := saveAscii(img, path, 4); err != nil {
+		t.Fatal(err)
+	}
+	text, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(text), "\x1b[38;2;255;255;255m@") || !strings.HasSuffix(string(text), "\x1b[0m\n") {
+		t.Errorf("ANSI text is missing its colors: %q", text)
+	}
+
+	if err := saveAscii(img, path, 0); err == nil {
+		t.Error("0 columns should be an error")
+	}
+}
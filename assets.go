@@ -0,0 +1,97 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: assets.go
+ * Description:
+ *   The assets built into the executable, with an optional directory on
+ *   disk whose files take their place.
+ */
+
+package main
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+)
+
+//go:embed assets
+var embeddedAssets embed.FS
+
+// The filesystem that every asset is loaded from. It starts out as the
+// built-in assets and is overlaid by useAssetsDir.
+var assetFS fs.FS = mustSub(embeddedAssets, "assets")
+
+/*
+ * Get the subtree of a filesystem, which can only fail for invalid names.
+ */
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+/*
+ * A filesystem whose upper files are used in place of lower files with the
+ * same name. Directories list the files of both.
+ */
+type overlayFS struct {
+	upper fs.FS
+	lower fs.FS
+}
+
+/*
+ * Open a file from the upper filesystem, or from the lower one if the
+ * upper one doesn't have it.
+ */
+func (o overlayFS) Open(name string) (fs.File, error) {
+	file, err := o.upper.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return o.lower.Open(name)
+	}
+	return file, err
+}
+
+/*
+ * List a directory of both filesystems, sorted by name.
+ */
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	upper, upperErr := fs.ReadDir(o.upper, name)
+	lower, lowerErr := fs.ReadDir(o.lower, name)
+	if upperErr != nil && lowerErr != nil {
+		return nil, upperErr
+	}
+
+	seen := make(map[string]bool)
+	entries := make([]fs.DirEntry, 0, len(upper)+len(lower))
+	for _, entry := range append(upper, lower...) {
+		if seen[entry.Name()] {
+			continue
+		}
+		seen[entry.Name()] = true
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i int, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+/*
+ * Load assets from dir wherever it has a file, and from the built-in
+ * assets otherwise.
+ */
+func useAssetsDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errors.New(dir + " is not a directory")
+	}
+	assetFS = overlayFS{os.DirFS(dir), mustSub(embeddedAssets, "assets")}
+	return nil
+}
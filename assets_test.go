@@ -0,0 +1,108 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: assets_test.go
+ * Description:
+ *   Tests for the built-in assets and the directory that can replace them.
+ */
+
+package main
+
+import (
+	"image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedAssets(t *testing.T) {
+	for _, name := range []string{"packs/cats/cat1.png", "packs/cats/cat2.png"} {
+		file, err := assetFS.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = png.Decode(file)
+		file.Close()
+		if err != nil {
+			t.Errorf("%s is not a PNG: %v", name, err)
+		}
+	}
+}
+
+func TestOverlayFS(t *testing.T) {
+	upper := fstest.MapFS{
+		"a.txt":     {Data: []byte("upper a")},
+		"dir/c.txt": {Data: []byte("upper c")},
+	}
+	lower := fstest.MapFS{
+		"a.txt":     {Data: []byte("lower a")},
+		"b.txt":     {Data: []byte("lower b")},
+		"dir/d.txt": {Data: []byte("lower d")},
+	}
+	overlay := overlayFS{upper, lower}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"a.txt", "upper a"},
+		{"b.txt", "lower b"},
+		{"dir/c.txt", "upper c"},
+		{"dir/d.txt", "lower d"},
+	}
+	for _, tt := range tests {
+		data, err := fs.ReadFile(overlay, tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != tt.want {
+			t.Errorf("%s = %q, want %q", tt.name, data, tt.want)
+		}
+	}
+	if _, err := overlay.Open("e.txt"); err == nil {
+		t.Error("a file in neither filesystem should not open")
+	}
+
+	entries, err := fs.ReadDir(overlay, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	if want := []string{"a.txt", "b.txt", "dir"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("listing gave %v, want %v", names, want)
+	}
+	if _, err := fs.ReadDir(overlay, "nowhere"); err == nil {
+		t.Error("listing a directory in neither filesystem should be an error")
+	}
+}
+
+func TestUseAssetsDir(t *testing.T) {
+	saved := assetFS
+	defer func() { assetFS = saved }()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "extra.txt"), []byte("extra"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := useAssetsDir(filepath.Join(dir, "extra.txt")); err == nil {
+		t.Error("a file should not be usable as the assets directory")
+	}
+	if err := useAssetsDir(filepath.Join(dir, "missing")); err == nil {
+		t.Error("a missing directory should not be usable")
+	}
+
+	if err := useAssetsDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := fs.ReadFile(assetFS, "extra.txt"); err != nil || string(data) != "extra" {
+		t.Errorf("the file from the directory gave %q, %v", data, err)
+	}
+	if _, err := fs.Stat(assetFS, "packs/cats/cat1.png"); err != nil {
+		t.Errorf("the built-in assets are hidden: %v", err)
+	}
+}
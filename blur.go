@@ -25,6 +25,11 @@ func BlurParallel(img image.Image) (image.Image, error) {
 		{1 / 16.0, 2 / 16.0, 1 / 16.0},
 	}
 
+	return convolveParallel(img, kernel), nil
+}
+
+// convolveParallel applies any square kernel to an image in parallel strips
+func convolveParallel(img image.Image, kernel [][]float64) *image.RGBA {
 	bounds := img.Bounds()
 	blurred := image.NewRGBA(bounds)
 
@@ -52,7 +57,7 @@ func BlurParallel(img image.Image) (image.Image, error) {
 	}
 
 	wg.Wait()
-	return blurred, nil
+	return blurred
 }
 
 // Worker function for parallel convolution
@@ -64,9 +69,6 @@ func applyConvolutionWorker(
 	workerID int,
 	workers int,
 ) {
-	kernelSize := len(kernel)
-	radius := kernelSize / 2
-
 	// Calculate this worker's strip of the image
 	stripHeight := bounds.Dy() / workers
 	yStart := bounds.Min.Y + workerID*stripHeight
@@ -77,23 +79,7 @@ func applyConvolutionWorker(
 
 	for y := yStart; y < yEnd; y++ {
 		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			var r, g, b, a float64
-
-			for ky := 0; ky < kernelSize; ky++ {
-				for kx := 0; kx < kernelSize; kx++ {
-					px := clamp(x+kx-radius, bounds.Min.X, bounds.Max.X-1)
-					py := clamp(y+ky-radius, bounds.Min.Y, bounds.Max.Y-1)
-
-					pixel := src.At(px, py)
-					pr, pg, pb, pa := pixel.RGBA()
-					weight := kernel[ky][kx]
-
-					r += float64(pr>>8) * weight
-					g += float64(pg>>8) * weight
-					b += float64(pb>>8) * weight
-					a += float64(pa>>8) * weight
-				}
-			}
+			r, g, b, a := convolvePixel(src, kernel, bounds, x, y)
 
 			dst.Set(x, y, color.RGBA{
 				R: uint8(math.Min(255, math.Max(0, r))),
@@ -105,6 +91,90 @@ func applyConvolutionWorker(
 	}
 }
 
+// convolvePixel applies a kernel centered on one pixel and returns the
+// unclamped 8-bit scale channel sums, so callers can keep negative values
+func convolvePixel(
+	src image.Image,
+	kernel [][]float64,
+	bounds image.Rectangle,
+	x int,
+	y int,
+) (r, g, b, a float64) {
+	kernelSize := len(kernel)
+	radius := kernelSize / 2
+
+	for ky := 0; ky < kernelSize; ky++ {
+		for kx := 0; kx < kernelSize; kx++ {
+			px := clamp(x+kx-radius, bounds.Min.X, bounds.Max.X-1)
+			py := clamp(y+ky-radius, bounds.Min.Y, bounds.Max.Y-1)
+
+			pixel := src.At(px, py)
+			pr, pg, pb, pa := pixel.RGBA()
+			weight := kernel[ky][kx]
+
+			r += float64(pr>>8) * weight
+			g += float64(pg>>8) * weight
+			b += float64(pb>>8) * weight
+			a += float64(pa>>8) * weight
+		}
+	}
+	return r, g, b, a
+}
+
+/*
+ * Get how far a Gaussian kernel reaches on each side: three standard
+ * deviations, and at least one pixel.
+ */
+func gaussianRadius(sigma float64) int {
+	return max(1, int(math.Ceil(sigma*3)))
+}
+
+/*
+ * Build a normalized one-dimensional Gaussian kernel that reaches radius
+ * pixels on each side, for blurring across and then down.
+ */
+func gaussianWeights(sigma float64, radius int) []float64 {
+	weights := make([]float64, radius*2+1)
+	var sum float64
+	for i := range weights {
+		d := float64(i - radius)
+		weights[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		sum += weights[i]
+	}
+	for i := range weights {
+		weights[i] /= sum
+	}
+	return weights
+}
+
+// gaussianKernel builds a normalized Gaussian kernel covering three
+// standard deviations on each side
+func gaussianKernel(sigma float64) [][]float64 {
+	radius := int(math.Ceil(sigma * 3))
+	if radius < 1 {
+		radius = 1
+	}
+	size := radius*2 + 1
+
+	kernel := make([][]float64, size)
+	var sum float64
+	for ky := 0; ky < size; ky++ {
+		kernel[ky] = make([]float64, size)
+		for kx := 0; kx < size; kx++ {
+			dx := float64(kx - radius)
+			dy := float64(ky - radius)
+			kernel[ky][kx] = math.Exp(-(dx*dx + dy*dy) / (2 * sigma * sigma))
+			sum += kernel[ky][kx]
+		}
+	}
+	for ky := range kernel {
+		for kx := range kernel[ky] {
+			kernel[ky][kx] /= sum
+		}
+	}
+	return kernel
+}
+
 // clamp ensures pixel coordinates stay within bounds
 func clamp(value, min, max int) int {
 	if value < min {
@@ -115,3 +185,27 @@ func clamp(value, min, max int) int {
 	}
 	return value
 }
+
+// mirror reflects pixel coordinates back into bounds, repeating the edge
+// pixel, e.g. abcd|dcba
+func mirror(value, min, max int) int {
+	period := 2 * (max - min + 1)
+	v := ((value-min)%period + period) % period
+	if v > max-min {
+		v = period - 1 - v
+	}
+	return min + v
+}
+
+// wrap tiles pixel coordinates, e.g. abcd|abcd
+func wrap(value, min, max int) int {
+	size := max - min + 1
+	return min + ((value-min)%size+size)%size
+}
+
+// EDGE_MODES are the ways of filling pixels past the border by name
+var EDGE_MODES = map[string]func(value, min, max int) int{
+	"replicate": clamp,
+	"mirror":    mirror,
+	"wrap":      wrap,
+}
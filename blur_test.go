@@ -0,0 +1,103 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: blur_test.go
+ * Description:
+ *   Tests for the Gaussian kernels and edge modes that the blurs share.
+ */
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGaussianRadius(t *testing.T) {
+	tests := []struct {
+		sigma float64
+		want  int
+	}{
+		{0.1, 1},
+		{0.34, 2},
+		{1, 3},
+		{1.4, 5},
+		{2, 6},
+		{8, 24},
+	}
+	for _, tt := range tests {
+		if got := gaussianRadius(tt.sigma); got != tt.want {
+			t.Errorf("gaussianRadius(%v) = %d, want %d", tt.sigma, got, tt.want)
+		}
+	}
+}
+
+func TestGaussianWeights(t *testing.T) {
+	tests := []struct {
+		sigma  float64
+		radius int
+	}{
+		{0.5, 1},
+		{1, 3},
+		{2.5, 8},
+		{3, 2},
+	}
+	for _, tt := range tests {
+		weights := gaussianWeights(tt.sigma, tt.radius)
+		if len(weights) != 2*tt.radius+1 {
+			t.Fatalf("gaussianWeights(%v, %d) has %d weights", tt.sigma, tt.radius, len(weights))
+		}
+		var sum float64
+		for i, w := range weights {
+			sum += w
+			if mirror := weights[len(weights)-1-i]; w != mirror {
+				t.Errorf("gaussianWeights(%v, %d) is not symmetric at %d", tt.sigma, tt.radius, i)
+			}
+			if i > 0 && i <= tt.radius && w <= weights[i-1] {
+				t.Errorf("gaussianWeights(%v, %d) does not rise to the middle", tt.sigma, tt.radius)
+			}
+		}
+		if math.Abs(sum-1) > 1e-12 {
+			t.Errorf("gaussianWeights(%v, %d) adds up to %v, want 1", tt.sigma, tt.radius, sum)
+		}
+	}
+}
+
+func TestGaussianWeightsRatio(t *testing.T) {
+	// Neighbors one sigma away get e^(-1/2) of the middle weight.
+	weights := gaussianWeights(2, 6)
+	if got, want := weights[4]/weights[6], math.Exp(-0.5); math.Abs(got-want) > 1e-12 {
+		t.Errorf("weight one sigma out is %v of the middle, want %v", got, want)
+	}
+}
+
+func TestEdgeModes(t *testing.T) {
+	tests := []struct {
+		mode          string
+		value, lo, hi int
+		want          int
+	}{
+		{"replicate", -3, 0, 3, 0},
+		{"replicate", 2, 0, 3, 2},
+		{"replicate", 9, 0, 3, 3},
+		{"mirror", -1, 0, 3, 0},
+		{"mirror", -2, 0, 3, 1},
+		{"mirror", 4, 0, 3, 3},
+		{"mirror", 5, 0, 3, 2},
+		{"mirror", 8, 0, 3, 0},
+		{"mirror", -9, 0, 3, 0},
+		{"mirror", 1, 2, 4, 2},
+		{"mirror", 6, 2, 4, 3},
+		{"mirror", 7, 5, 5, 5},
+		{"wrap", -1, 0, 3, 3},
+		{"wrap", 4, 0, 3, 0},
+		{"wrap", 9, 0, 3, 1},
+		{"wrap", 1, 2, 4, 4},
+		{"wrap", 5, 2, 4, 2},
+		{"wrap", -7, 5, 5, 5},
+	}
+	for _, tt := range tests {
+		if got := EDGE_MODES[tt.mode](tt.value, tt.lo, tt.hi); got != tt.want {
+			t.Errorf("%s(%d, %d, %d) = %d, want %d", tt.mode, tt.value, tt.lo, tt.hi, got, tt.want)
+		}
+	}
+}
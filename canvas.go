@@ -0,0 +1,314 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: canvas.go
+ * Description:
+ *   Grow or crop the canvas around an image: padding, letterboxing to a
+ *   fixed size or aspect ratio, and rounded borders.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/*
+ * Where a smaller rectangle can be placed inside of a larger one, as
+ * fractions of the free space across and down.
+ */
+var GRAVITIES = map[string][2]float64{
+	"northwest": {0, 0},
+	"north":     {0.5, 0},
+	"northeast": {1, 0},
+	"west":      {0, 0.5},
+	"center":    {0.5, 0.5},
+	"east":      {1, 0.5},
+	"southwest": {0, 1},
+	"south":     {0.5, 1},
+	"southeast": {1, 1},
+}
+
+/*
+ * Get where a rectangle of size inner goes inside of one of size outer.
+ * The offset is negative when inner is the larger one.
+ */
+func gravityOffset(gravity string, outer image.Point, inner image.Point) (image.Point, error) {
+	g, prs := GRAVITIES[gravity]
+	if !prs {
+		return image.Point{}, fmt.Errorf("%s is not a valid gravity", gravity)
+	}
+	return image.Pt(
+		int(math.Round(float64(outer.X-inner.X)*g[0])),
+		int(math.Round(float64(outer.Y-inner.Y)*g[1])),
+	), nil
+}
+
+/*
+ * Get how much of a pixel centered at (x, y) is inside of a rectangle with
+ * corners rounded by radius, from 0 to 1, with edges softened over about
+ * one pixel.
+ */
+func roundedRectCoverage(x float64, y float64, rect image.Rectangle, radius float64) float64 {
+	cx := float64(rect.Min.X+rect.Max.X) / 2
+	cy := float64(rect.Min.Y+rect.Max.Y) / 2
+	halfW := float64(rect.Dx()) / 2
+	halfH := float64(rect.Dy()) / 2
+	radius = math.Min(radius, math.Min(halfW, halfH))
+
+	// Signed distance to the edge, which is negative inside.
+	qx := math.Abs(x-cx) - halfW + radius
+	qy := math.Abs(y-cy) - halfH + radius
+	d := math.Hypot(math.Max(qx, 0), math.Max(qy, 0)) + math.Min(math.Max(qx, qy), 0) - radius
+	return math.Min(1, math.Max(0, 0.5-d))
+}
+
+/*
+ * Place an image on a new canvas of the given size, with its corner at
+ * offset. Canvas pixels that the image doesn't cover are filled with fill
+ * when mode is "color" or the image is empty, and with one of the
+ * EDGE_MODES otherwise. Rows are split between workers.
+ */
+func extendCanvas(img image.Image, size image.Point, offset image.Point, mode string, fill color.NRGBA) *image.RGBA {
+	src := toRGBA(img)
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rectangle{image.Point{}, size})
+	fillRGBA := color.RGBAModel.Convert(fill).(color.RGBA)
+	edge := EDGE_MODES[mode]
+	if bounds.Empty() {
+		// An empty image has no edge to repeat, so the canvas is all fill.
+		edge = nil
+	}
+
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(0, size.Y, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := 0; x < size.X; x++ {
+				sx := x - offset.X + bounds.Min.X
+				sy := y - offset.Y + bounds.Min.Y
+				inside := image.Pt(sx, sy).In(bounds)
+
+				var clr color.RGBA
+				switch {
+				case inside:
+					clr = src.RGBAAt(sx, sy)
+				case edge == nil:
+					clr = fillRGBA
+				default:
+					clr = src.RGBAAt(
+						edge(sx, bounds.Min.X, bounds.Max.X-1),
+						edge(sy, bounds.Min.Y, bounds.Max.Y-1),
+					)
+				}
+				dst.SetRGBA(x, y, clr)
+			}
+		}
+	})
+	return dst
+}
+
+/*
+ * Get a function that will add space around any image, filled with fill or
+ * by one of the edge modes.
+ */
+func PadT(top int, right int, bottom int, left int, mode string, fill color.NRGBA) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		size := img.Bounds().Size().Add(image.Pt(left+right, top+bottom))
+		return extendCanvas(img, size, image.Pt(left, top), mode, fill), nil
+	}
+}
+
+/*
+ * Get a function that will place any image on a canvas, placed by gravity.
+ * The canvas is width by height, or if aspect is above 0, the smallest
+ * canvas with that width to height ratio that fits the whole image. Images
+ * larger than the canvas are cropped.
+ */
+func CanvasT(width int, height int, aspect float64, gravity string, mode string, fill color.NRGBA) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		inner := img.Bounds().Size()
+		size := image.Pt(width, height)
+		if aspect > 0 {
+			size = inner
+			if float64(inner.X)/float64(inner.Y) < aspect {
+				size.X = int(math.Round(float64(inner.Y) * aspect))
+			} else {
+				size.Y = int(math.Round(float64(inner.X) / aspect))
+			}
+		}
+
+		offset, err := gravityOffset(gravity, size, inner)
+		if err != nil {
+			return nil, err
+		}
+		return extendCanvas(img, size, offset, mode, fill), nil
+	}
+}
+
+/*
+ * Get a function that will frame any image with a border of the given
+ * width. The outside corners of the frame are rounded by radius, and the
+ * corners of the image inside by what is left of radius after the width.
+ */
+func BorderT(width int, clr color.NRGBA, radius float64) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		src := toRGBA(img)
+		bounds := src.Bounds()
+		size := bounds.Size().Add(image.Pt(2*width, 2*width))
+		dst := image.NewRGBA(image.Rectangle{image.Point{}, size})
+
+		outer := dst.Bounds()
+		inner := outer.Inset(width)
+		innerRadius := math.Max(0, radius-float64(width))
+		frame := [4]float64{
+			float64(clr.R) * float64(clr.A) / 255,
+			float64(clr.G) * float64(clr.A) / 255,
+			float64(clr.B) * float64(clr.A) / 255,
+			float64(clr.A),
+		}
+
+		pool := GetGlobalWorkers()
+		pool.ParallelFor(0, size.Y, func(lo int, hi int) {
+			for y := lo; y < hi; y++ {
+				for x := 0; x < size.X; x++ {
+					px, py := float64(x)+0.5, float64(y)+0.5
+					outerCov := roundedRectCoverage(px, py, outer, radius)
+					innerCov := roundedRectCoverage(px, py, inner, innerRadius)
+					if outerCov == 0 {
+						continue
+					}
+
+					var pixel [4]float64
+					if innerCov > 0 {
+						p := src.RGBAAt(x-width+bounds.Min.X, y-width+bounds.Min.Y)
+						pixel = [4]float64{float64(p.R), float64(p.G), float64(p.B), float64(p.A)}
+					}
+					i := dst.PixOffset(x, y)
+					for c := 0; c < 4; c++ {
+						v := pixel[c]*innerCov + frame[c]*(outerCov-innerCov)
+						dst.Pix[i+c] = uint8(math.Round(v))
+					}
+				}
+			}
+		})
+		return dst, nil
+	}
+}
+
+/*
+ * Read the edge mode and fill color shared by pad and canvas.
+ */
+func canvasFill(args tfmArgs) (string, color.NRGBA, error) {
+	mode := args.getString("mode", "color")
+	if _, prs := EDGE_MODES[mode]; !prs && mode != "color" {
+		return "", color.NRGBA{}, fmt.Errorf("%s is not a valid mode", mode)
+	}
+	fill, err := parseColor(args.getString("color", "transparent"))
+	if err != nil {
+		return "", color.NRGBA{}, err
+	}
+	return mode, fill, nil
+}
+
+/*
+ * Build a pad transformation from its command line arguments. size sets
+ * every side that isn't given on its own.
+ */
+func padFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("size", "top", "right", "bottom", "left", "color", "mode"); err != nil {
+		return nil, err
+	}
+
+	size, err := args.getInt("size", 0)
+	if err != nil {
+		return nil, err
+	}
+	var sides [4]int
+	for i, key := range []string{"top", "right", "bottom", "left"} {
+		sides[i], err = args.getInt(key, size)
+		if err != nil {
+			return nil, err
+		}
+		if sides[i] < 0 {
+			return nil, fmt.Errorf("%s must not be negative", key)
+		}
+	}
+	mode, fill, err := canvasFill(args)
+	if err != nil {
+		return nil, err
+	}
+	return PadT(sides[0], sides[1], sides[2], sides[3], mode, fill), nil
+}
+
+/*
+ * Build a canvas transformation from its command line arguments. The
+ * aspect ratio is written as w:h, e.g. 16:9.
+ */
+func canvasFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("width", "height", "aspect", "gravity", "color", "mode"); err != nil {
+		return nil, err
+	}
+
+	width, err := args.getInt("width", 0)
+	if err != nil {
+		return nil, err
+	}
+	height, err := args.getInt("height", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	aspect := 0.0
+	if args.has("aspect") {
+		w, h, found := strings.Cut(args.getString("aspect", ""), ":")
+		aw, errW := strconv.ParseFloat(w, 64)
+		ah, errH := strconv.ParseFloat(h, 64)
+		if !found || errW != nil || errH != nil || aw <= 0 || ah <= 0 {
+			return nil, errors.New("aspect must be written as w:h")
+		}
+		aspect = aw / ah
+	} else if width <= 0 || height <= 0 {
+		return nil, errors.New("width and height, or aspect, are required")
+	}
+
+	gravity := args.getString("gravity", "center")
+	if _, prs := GRAVITIES[gravity]; !prs {
+		return nil, fmt.Errorf("%s is not a valid gravity", gravity)
+	}
+	mode, fill, err := canvasFill(args)
+	if err != nil {
+		return nil, err
+	}
+	return CanvasT(width, height, aspect, gravity, mode, fill), nil
+}
+
+/*
+ * Build a border transformation from its command line arguments.
+ */
+func borderFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("width", "color", "radius"); err != nil {
+		return nil, err
+	}
+
+	width, err := args.getInt("width", 10)
+	if err != nil {
+		return nil, err
+	}
+	clr, err := parseColor(args.getString("color", "white"))
+	if err != nil {
+		return nil, err
+	}
+	radius, err := args.getFloat("radius", 0)
+	if err != nil {
+		return nil, err
+	}
+	if width < 0 || radius < 0 {
+		return nil, errors.New("width and radius must not be negative")
+	}
+	return BorderT(width, clr, radius), nil
+}
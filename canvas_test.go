@@ -0,0 +1,191 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: canvas_test.go
+ * Description:
+ *   Tests for padding, canvases and borders.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestGravityOffset(t *testing.T) {
+	tests := []struct {
+		gravity      string
+		outer, inner image.Point
+		want         image.Point
+	}{
+		{"northwest", image.Pt(10, 8), image.Pt(4, 4), image.Pt(0, 0)},
+		{"center", image.Pt(10, 8), image.Pt(4, 4), image.Pt(3, 2)},
+		{"southeast", image.Pt(10, 8), image.Pt(4, 4), image.Pt(6, 4)},
+		{"east", image.Pt(10, 8), image.Pt(4, 3), image.Pt(6, 3)},
+		{"south", image.Pt(4, 4), image.Pt(10, 8), image.Pt(-3, -4)},
+	}
+	for _, tt := range tests {
+		got, err := gravityOffset(tt.gravity, tt.outer, tt.inner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("gravityOffset(%s, %v, %v) = %v, want %v", tt.gravity, tt.outer, tt.inner, got, tt.want)
+		}
+	}
+	if _, err := gravityOffset("up", image.Pt(1, 1), image.Pt(1, 1)); err == nil {
+		t.Error("up should not be a valid gravity")
+	}
+}
+
+func TestRoundedRectCoverage(t *testing.T) {
+	rect := image.Rect(0, 0, 20, 10)
+	tests := []struct {
+		x, y   float64
+		radius float64
+		want   float64
+	}{
+		{10, 5, 0, 1},
+		{0.5, 0.5, 0, 1},
+		{-0.5, 5, 0, 0},
+		{0, 5, 0, 0.5},
+		{0.5, 0.5, 4, 0},
+		{4, 4, 4, 1},
+		{10, 0, 4, 0.5},
+		{30, 30, 4, 0},
+	}
+	for _, tt := range tests {
+		if got := roundedRectCoverage(tt.x, tt.y, rect, tt.radius); got != tt.want {
+			t.Errorf("coverage at (%v, %v) with radius %v = %v, want %v", tt.x, tt.y, tt.radius, got, tt.want)
+		}
+	}
+}
+
+func TestExtendCanvasModes(t *testing.T) {
+	// One row of three pixels, a, b and c, padded by two on both sides.
+	a, b, c := color.RGBA{10, 0, 0, 255}, color.RGBA{20, 0, 0, 255}, color.RGBA{30, 0, 0, 255}
+	fill := color.RGBA{0, 0, 99, 255}
+	img := image.NewRGBA(image.Rect(5, 5, 8, 6))
+	img.SetRGBA(5, 5, a)
+	img.SetRGBA(6, 5, b)
+	img.SetRGBA(7, 5, c)
+
+	tests := []struct {
+		mode string
+		want []color.RGBA
+	}{
+		{"color", []color.RGBA{fill, fill, a, b, c, fill, fill}},
+		{"replicate", []color.RGBA{a, a, a, b, c, c, c}},
+		{"mirror", []color.RGBA{b, a, a, b, c, c, b}},
+		{"wrap", []color.RGBA{b, c, a, b, c, a, b}},
+	}
+	for _, tt := range tests {
+		dst := extendCanvas(img, image.Pt(7, 1), image.Pt(2, 0), tt.mode, color.NRGBA{0, 0, 99, 255})
+		if dst.Bounds() != image.Rect(0, 0, 7, 1) {
+			t.Fatalf("%s: canvas covers %v", tt.mode, dst.Bounds())
+		}
+		for x, want := range tt.want {
+			if got := dst.RGBAAt(x, 0); got != want {
+				t.Errorf("%s: pixel %d = %v, want %v", tt.mode, x, got, want)
+			}
+		}
+	}
+}
+
+func TestExtendCanvasEmptySource(t *testing.T) {
+	for mode := range EDGE_MODES {
+		dst := extendCanvas(image.NewRGBA(image.Rect(0, 0, 0, 0)), image.Pt(3, 2), image.Point{}, mode, color.NRGBA{255, 0, 0, 255})
+		for y := 0; y < 2; y++ {
+			for x := 0; x < 3; x++ {
+				if got := dst.RGBAAt(x, y); got != (color.RGBA{255, 0, 0, 255}) {
+					t.Errorf("%s: pixel at (%d, %d) of an empty image's canvas is %v", mode, x, y, got)
+				}
+			}
+		}
+	}
+}
+
+func TestPadAndCanvasSizes(t *testing.T) {
+	img := flatImage(40, 20, color.White)
+	clear := color.NRGBA{}
+	tests := []struct {
+		name string
+		tfm  func(image.Image) (image.Image, error)
+		want image.Point
+	}{
+		{"pad", PadT(1, 2, 3, 4, "color", clear), image.Pt(46, 24)},
+		{"canvas", CanvasT(50, 50, 0, "center", "color", clear), image.Pt(50, 50)},
+		{"smaller canvas", CanvasT(10, 10, 0, "center", "color", clear), image.Pt(10, 10)},
+		{"square", CanvasT(0, 0, 1, "center", "color", clear), image.Pt(40, 40)},
+		{"wide", CanvasT(0, 0, 4, "center", "color", clear), image.Pt(80, 20)},
+		{"16:9", CanvasT(0, 0, 16.0/9, "center", "color", clear), image.Pt(40, 23)},
+	}
+	for _, tt := range tests {
+		out, err := tt.tfm(img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := out.Bounds(); got != (image.Rectangle{Max: tt.want}) {
+			t.Errorf("%s: output covers %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBorder(t *testing.T) {
+	img := flatImage(10, 10, color.RGBA{0, 0, 255, 255})
+	out, err := BorderT(3, color.NRGBA{255, 0, 0, 255}, 0)(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := out.(*image.RGBA)
+	if got := dst.Bounds(); got != image.Rect(0, 0, 16, 16) {
+		t.Fatalf("bordered image covers %v", got)
+	}
+	if got := dst.RGBAAt(0, 0); got != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("the frame is %v", got)
+	}
+	if got := dst.RGBAAt(3, 3); got != (color.RGBA{0, 0, 255, 255}) {
+		t.Errorf("the image inside the frame is %v", got)
+	}
+
+	// Rounded corners leave the outside corner clear.
+	out, err = BorderT(3, color.NRGBA{255, 0, 0, 255}, 6)(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst = out.(*image.RGBA)
+	if got := dst.RGBAAt(0, 0); got != (color.RGBA{}) {
+		t.Errorf("the rounded corner is %v", got)
+	}
+	if got := dst.RGBAAt(8, 0); got != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("the middle of the top of the frame is %v", got)
+	}
+}
+
+func TestCanvasArgs(t *testing.T) {
+	tests := []struct {
+		commands string
+		ok       bool
+	}{
+		{"pad,size=4", true},
+		{"pad,top=1,left=2,mode=mirror", true},
+		{"pad,size=-1", false},
+		{"pad,mode=stretch", false},
+		{"canvas,width=10,height=5,gravity=north,color=#ffffff", true},
+		{"canvas,aspect=16:9,mode=wrap", true},
+		{"canvas", false},
+		{"canvas,aspect=16", false},
+		{"canvas,aspect=0:9", false},
+		{"canvas,width=10,height=5,gravity=up", false},
+		{"border", true},
+		{"border,width=0,color=#000000,radius=8", true},
+		{"border,radius=-1", false},
+	}
+	for _, tt := range tests {
+		_, err := TokensToTfms(SplitCommands(tt.commands), 1)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.commands, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: carve.go
+ * Description:
+ *   Content-aware resizing with seam carving. Low-energy seams are
+ *   removed to shrink an image and duplicated to grow it, and mask images
+ *   can protect regions or mark them for removal.
+ */
+
+package main
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"math"
+)
+
+// Energy added to protected pixels and taken away from pixels marked for
+// removal, which is far more than any gradient can reach.
+const MASK_ENERGY = 1e7
+
+/*
+ * An image being carved, stored row by row. Every pixel also has a mask
+ * value (1 to protect it, -1 to remove it, 0 otherwise) and the column it
+ * came from.
+ */
+type carveImage struct {
+	w      int
+	h      int
+	pix    []color.RGBA
+	mask   []int8
+	origin []int
+}
+
+/*
+ * Copy an image into a carve image. Either mask may be nil; otherwise it
+ * must be the same size as the image, and its bright pixels are the ones
+ * that are protected or removed.
+ */
+func newCarveImage(img image.Image, protect image.Image, remove image.Image) (*carveImage, error) {
+	src := toRGBA(img)
+	bounds := src.Bounds()
+	c := &carveImage{
+		bounds.Dx(), bounds.Dy(),
+		make([]color.RGBA, bounds.Dx()*bounds.Dy()),
+		make([]int8, bounds.Dx()*bounds.Dy()),
+		make([]int, bounds.Dx()*bounds.Dy()),
+	}
+
+	for _, mask := range []image.Image{protect, remove} {
+		if mask != nil && mask.Bounds().Size() != bounds.Size() {
+			return nil, errors.New("masks must be the same size as the image")
+		}
+	}
+	marked := func(mask image.Image, x int, y int) bool {
+		if mask == nil {
+			return false
+		}
+		mb := mask.Bounds()
+		return color.GrayModel.Convert(mask.At(mb.Min.X+x, mb.Min.Y+y)).(color.Gray).Y >= 128
+	}
+
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(0, c.h, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := 0; x < c.w; x++ {
+				i := y*c.w + x
+				c.pix[i] = src.RGBAAt(bounds.Min.X+x, bounds.Min.Y+y)
+				c.origin[i] = x
+				if marked(remove, x, y) {
+					c.mask[i] = -1
+				} else if marked(protect, x, y) {
+					c.mask[i] = 1
+				}
+			}
+		}
+	})
+	return c, nil
+}
+
+/*
+ * Swap the rows and columns, so that horizontal seams can be carved as
+ * vertical ones. The columns pixels came from are reset.
+ */
+func (c *carveImage) transpose() *carveImage {
+	t := &carveImage{
+		c.h, c.w,
+		make([]color.RGBA, len(c.pix)),
+		make([]int8, len(c.mask)),
+		make([]int, len(c.origin)),
+	}
+
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(0, t.h, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := 0; x < t.w; x++ {
+				t.pix[y*t.w+x] = c.pix[x*c.w+y]
+				t.mask[y*t.w+x] = c.mask[x*c.w+y]
+				t.origin[y*t.w+x] = x
+			}
+		}
+	})
+	return t
+}
+
+/*
+ * Get the energy of every pixel: how much its neighbors differ from each
+ * other across and down, plus the mask. Rows are split between workers.
+ */
+func (c *carveImage) energy() []float64 {
+	energy := make([]float64, len(c.pix))
+	diff := func(p color.RGBA, q color.RGBA) float64 {
+		dr := float64(p.R) - float64(q.R)
+		dg := float64(p.G) - float64(q.G)
+		db := float64(p.B) - float64(q.B)
+		da := float64(p.A) - float64(q.A)
+		return dr*dr + dg*dg + db*db + da*da
+	}
+
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(0, c.h, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			up, down := max(0, y-1), min(c.h-1, y+1)
+			for x := 0; x < c.w; x++ {
+				left, right := max(0, x-1), min(c.w-1, x+1)
+				e := math.Sqrt(
+					diff(c.pix[y*c.w+left], c.pix[y*c.w+right]) +
+						diff(c.pix[up*c.w+x], c.pix[down*c.w+x]),
+				)
+				energy[y*c.w+x] = e + float64(c.mask[y*c.w+x])*MASK_ENERGY
+			}
+		}
+	})
+	return energy
+}
+
+/*
+ * Find the connected top-to-bottom seam with the least total energy using
+ * dynamic programming.
+ *
+ * Returns: The column of the seam in every row.
+ */
+func (c *carveImage) findSeam(energy []float64) []int {
+	cost := make([]float64, len(energy))
+	copy(cost[:c.w], energy[:c.w])
+	for y := 1; y < c.h; y++ {
+		prev := cost[(y-1)*c.w : y*c.w]
+		for x := 0; x < c.w; x++ {
+			best := prev[x]
+			if x > 0 {
+				best = math.Min(best, prev[x-1])
+			}
+			if x < c.w-1 {
+				best = math.Min(best, prev[x+1])
+			}
+			cost[y*c.w+x] = energy[y*c.w+x] + best
+		}
+	}
+
+	seam := make([]int, c.h)
+	last := cost[(c.h-1)*c.w:]
+	for x := range last {
+		if last[x] < last[seam[c.h-1]] {
+			seam[c.h-1] = x
+		}
+	}
+	// Walk back up, always stepping to the cheapest neighbor above.
+	for y := c.h - 2; y >= 0; y-- {
+		x := seam[y+1]
+		best := x
+		for _, nx := range []int{x - 1, x + 1} {
+			if nx >= 0 && nx < c.w && cost[y*c.w+nx] < cost[y*c.w+best] {
+				best = nx
+			}
+		}
+		seam[y] = best
+	}
+	return seam
+}
+
+/*
+ * Make a copy of the image without the given seam.
+ */
+func (c *carveImage) removeSeam(seam []int) *carveImage {
+	n := &carveImage{
+		c.w - 1, c.h,
+		make([]color.RGBA, (c.w-1)*c.h),
+		make([]int8, (c.w-1)*c.h),
+		make([]int, (c.w-1)*c.h),
+	}
+
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(0, c.h, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			from, to := y*c.w, y*n.w
+			s := seam[y]
+			copy(n.pix[to:to+s], c.pix[from:from+s])
+			copy(n.pix[to+s:to+n.w], c.pix[from+s+1:from+c.w])
+			copy(n.mask[to:to+s], c.mask[from:from+s])
+			copy(n.mask[to+s:to+n.w], c.mask[from+s+1:from+c.w])
+			copy(n.origin[to:to+s], c.origin[from:from+s])
+			copy(n.origin[to+s:to+n.w], c.origin[from+s+1:from+c.w])
+		}
+	})
+	return n
+}
+
+/*
+ * Make a copy of the image with count new seams. The seams that would be
+ * removed first are found on a copy and then duplicated, each new pixel
+ * being the average of the seam pixel and its right neighbor.
+ */
+func (c *carveImage) insertSeams(count int) *carveImage {
+	// Number the columns afresh, since earlier carving may have left gaps.
+	origin := make([]int, len(c.origin))
+	for i := range origin {
+		origin[i] = i % c.w
+	}
+	carved := &carveImage{c.w, c.h, c.pix, c.mask, origin}
+
+	dups := make([]int, len(c.pix))
+	for i := 0; i < count; i++ {
+		seam := carved.findSeam(carved.energy())
+		for y, x := range seam {
+			dups[y*c.w+carved.origin[y*carved.w+x]]++
+		}
+		carved = carved.removeSeam(seam)
+	}
+
+	n := &carveImage{
+		c.w + count, c.h,
+		make([]color.RGBA, (c.w+count)*c.h),
+		make([]int8, (c.w+count)*c.h),
+		make([]int, (c.w+count)*c.h),
+	}
+
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(0, c.h, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			to := y * n.w
+			for x := 0; x < c.w; x++ {
+				i := y*c.w + x
+				p := c.pix[i]
+				q := c.pix[y*c.w+min(c.w-1, x+1)]
+				avg := color.RGBA{
+					uint8((uint16(p.R) + uint16(q.R)) / 2),
+					uint8((uint16(p.G) + uint16(q.G)) / 2),
+					uint8((uint16(p.B) + uint16(q.B)) / 2),
+					uint8((uint16(p.A) + uint16(q.A)) / 2),
+				}
+				for k := 0; k <= dups[i]; k++ {
+					n.pix[to] = p
+					if k > 0 {
+						n.pix[to] = avg
+					}
+					n.mask[to] = c.mask[i]
+					n.origin[to] = to - y*n.w
+					to++
+				}
+			}
+		}
+	})
+	return n
+}
+
+/*
+ * Change the width of the image to width by removing or inserting seams.
+ * Seams are inserted in batches of at most half the width, so the same
+ * seam isn't stretched over and over.
+ */
+func (c *carveImage) carveWidth(width int) *carveImage {
+	for c.w > width {
+		c = c.removeSeam(c.findSeam(c.energy()))
+	}
+	for c.w < width {
+		c = c.insertSeams(min(width-c.w, max(1, c.w/2)))
+	}
+	return c
+}
+
+/*
+ * Whether any pixel is still marked for removal.
+ */
+func (c *carveImage) hasRemoval() bool {
+	for _, m := range c.mask {
+		if m < 0 {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+ * Copy the carve image back into an RGBA image at origin.
+ */
+func (c *carveImage) toRGBA(origin image.Point) *image.RGBA {
+	dst := image.NewRGBA(image.Rectangle{origin, origin.Add(image.Pt(c.w, c.h))})
+	for y := 0; y < c.h; y++ {
+		for x := 0; x < c.w; x++ {
+			dst.SetRGBA(origin.X+x, origin.Y+y, c.pix[y*c.w+x])
+		}
+	}
+	return dst
+}
+
+/*
+ * Get a function that will resize any image to width by height with seam
+ * carving, so that the important parts keep their shape. A width or height
+ * of 0 keeps that side as it is.
+ *
+ * Pixels that are bright in protect are never carved while there is any
+ * other choice. Pixels that are bright in remove are carved out first with
+ * vertical seams, after which the image is brought back to its width
+ * unless another width was asked for.
+ */
+func CarveT(width int, height int, protect image.Image, remove image.Image) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		// Seams need at least one pixel in every row and column.
+		if img.Bounds().Empty() {
+			return nil, errors.New("there is nothing to carve in an empty image")
+		}
+		c, err := newCarveImage(img, protect, remove)
+		if err != nil {
+			return nil, err
+		}
+
+		w, h := width, height
+		if w == 0 {
+			w = c.w
+		}
+		if h == 0 {
+			h = c.h
+		}
+
+		for c.w > 1 && c.hasRemoval() {
+			c = c.removeSeam(c.findSeam(c.energy()))
+		}
+		c = c.carveWidth(w)
+		if h != c.h {
+			c = c.transpose().carveWidth(h).transpose()
+		}
+		return c.toRGBA(img.Bounds().Min), nil
+	}
+}
+
+/*
+ * Build a carve transformation from its command line arguments.
+ */
+func carveFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("width", "height", "protect", "remove"); err != nil {
+		return nil, err
+	}
+
+	width, err := args.getInt("width", 0)
+	if err != nil {
+		return nil, err
+	}
+	height, err := args.getInt("height", 0)
+	if err != nil {
+		return nil, err
+	}
+	if width < 0 || height < 0 {
+		return nil, errors.New("width and height must not be negative")
+	}
+
+	var masks [2]image.Image
+	for i, key := range []string{"protect", "remove"} {
+		if !args.has(key) {
+			continue
+		}
+		masks[i], err = decodeImage(args.getString(key, ""))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if width == 0 && height == 0 && masks[1] == nil {
+		return nil, errors.New("width, height or remove is required")
+	}
+	return CarveT(width, height, masks[0], masks[1]), nil
+}
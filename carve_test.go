@@ -0,0 +1,260 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: carve_test.go
+ * Description:
+ *   Tests for seam carving.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+/*
+ * Make a carve image of the given size with random pixels.
+ */
+func randomCarveImage(rng *rand.Rand, w int, h int) *carveImage {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	rng.Read(img.Pix)
+	c, err := newCarveImage(img, nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func TestFindSeamKnownEnergy(t *testing.T) {
+	tests := []struct {
+		w, h   int
+		energy []float64
+		want   []int
+	}{
+		{3, 3, []float64{
+			1, 9, 9,
+			9, 1, 9,
+			9, 9, 1,
+		}, []int{0, 1, 2}},
+		{4, 3, []float64{
+			5, 5, 0, 5,
+			5, 0, 5, 5,
+			0, 5, 5, 5,
+		}, []int{2, 1, 0}},
+		// Starting from the cheapest top pixel would miss the cheap
+		// bottom corner.
+		{4, 2, []float64{
+			1, 3, 2, 3,
+			5, 5, 9, 0,
+		}, []int{2, 3}},
+		{1, 3, []float64{4, 4, 4}, []int{0, 0, 0}},
+	}
+	for _, tt := range tests {
+		c := &carveImage{w: tt.w, h: tt.h}
+		if got := c.findSeam(tt.energy); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("findSeam(%v) = %v, want %v", tt.energy, got, tt.want)
+		}
+	}
+}
+
+func TestFindSeamMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 30; trial++ {
+		w, h := 1+rng.Intn(5), 1+rng.Intn(5)
+		energy := make([]float64, w*h)
+		for i := range energy {
+			energy[i] = float64(rng.Intn(20))
+		}
+
+		// Try every connected seam.
+		best := math.Inf(1)
+		var walk func(y int, x int, total float64)
+		walk = func(y int, x int, total float64) {
+			if x < 0 || x >= w {
+				return
+			}
+			total += energy[y*w+x]
+			if y == h-1 {
+				best = math.Min(best, total)
+				return
+			}
+			for dx := -1; dx <= 1; dx++ {
+				walk(y+1, x+dx, total)
+			}
+		}
+		for x := 0; x < w; x++ {
+			walk(0, x, 0)
+		}
+
+		c := &carveImage{w: w, h: h}
+		seam := c.findSeam(energy)
+		total := 0.0
+		for y, x := range seam {
+			if y > 0 && (x-seam[y-1] > 1 || seam[y-1]-x > 1) {
+				t.Fatalf("seam %v is not connected", seam)
+			}
+			total += energy[y*w+x]
+		}
+		if total != best {
+			t.Errorf("%dx%d: seam costs %v, want %v", w, h, total, best)
+		}
+	}
+}
+
+func TestRemoveSeam(t *testing.T) {
+	c := randomCarveImage(rand.New(rand.NewSource(2)), 5, 3)
+	seam := []int{0, 2, 4}
+	n := c.removeSeam(seam)
+	if n.w != 4 || n.h != 3 || len(n.pix) != 12 {
+		t.Fatalf("removing a seam from 5x3 gave %dx%d", n.w, n.h)
+	}
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 4; x++ {
+			from := x
+			if x >= seam[y] {
+				from++
+			}
+			if n.pix[y*4+x] != c.pix[y*5+from] || n.origin[y*4+x] != from {
+				t.Errorf("pixel (%d, %d) did not come from column %d", x, y, from)
+			}
+		}
+	}
+}
+
+func TestInsertSeams(t *testing.T) {
+	c := randomCarveImage(rand.New(rand.NewSource(3)), 6, 4)
+	n := c.insertSeams(3)
+	if n.w != 9 || n.h != 4 || len(n.pix) != 36 {
+		t.Fatalf("inserting 3 seams into 6x4 gave %dx%d", n.w, n.h)
+	}
+	// Every row keeps its pixels in order, with new ones in between.
+	for y := 0; y < 4; y++ {
+		x := 0
+		for nx := 0; nx < n.w && x < c.w; nx++ {
+			if n.pix[y*n.w+nx] == c.pix[y*c.w+x] {
+				x++
+			}
+		}
+		if x != c.w {
+			t.Errorf("row %d lost pixels", y)
+		}
+	}
+}
+
+func TestTransposeTwice(t *testing.T) {
+	c := randomCarveImage(rand.New(rand.NewSource(4)), 7, 3)
+	c.mask[5] = 1
+	tt := c.transpose()
+	if tt.w != 3 || tt.h != 7 || tt.pix[1*3+0] != c.pix[0*7+1] {
+		t.Fatal("transpose did not swap rows and columns")
+	}
+	back := tt.transpose()
+	if !reflect.DeepEqual(back.pix, c.pix) || !reflect.DeepEqual(back.mask, c.mask) {
+		t.Error("transposing twice changed the image")
+	}
+}
+
+func TestCarveSizes(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	img := image.NewRGBA(image.Rect(3, 4, 23, 16))
+	rng.Read(img.Pix)
+	tests := []struct {
+		width, height int
+		want          image.Point
+	}{
+		{15, 0, image.Pt(15, 12)},
+		{0, 8, image.Pt(20, 8)},
+		{26, 15, image.Pt(26, 15)},
+		{1, 1, image.Pt(1, 1)},
+	}
+	for _, tt := range tests {
+		out, err := CarveT(tt.width, tt.height, nil, nil)(img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := out.Bounds(); got != (image.Rectangle{Min: img.Rect.Min, Max: img.Rect.Min.Add(tt.want)}) {
+			t.Errorf("carving to %dx%d covers %v", tt.width, tt.height, got)
+		}
+	}
+}
+
+func TestCarveMasks(t *testing.T) {
+	// Gray noise with a red column and a blue column.
+	rng := rand.New(rand.NewSource(6))
+	img := image.NewRGBA(image.Rect(0, 0, 12, 8))
+	protect := image.NewGray(img.Bounds())
+	remove := image.NewGray(img.Bounds())
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 12; x++ {
+			v := uint8(100 + rng.Intn(20))
+			img.SetRGBA(x, y, color.RGBA{v, v, v, 255})
+		}
+		img.SetRGBA(3, y, color.RGBA{255, 0, 0, 255})
+		img.SetRGBA(8, y, color.RGBA{0, 0, 255, 255})
+		protect.SetGray(3, y, color.Gray{255})
+		remove.SetGray(8, y, color.Gray{255})
+	}
+
+	out, err := CarveT(6, 0, protect, remove)(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := out.(*image.RGBA)
+	for y := 0; y < 8; y++ {
+		red, blue := 0, 0
+		for x := 0; x < 6; x++ {
+			switch dst.RGBAAt(x, y) {
+			case color.RGBA{255, 0, 0, 255}:
+				red++
+			case color.RGBA{0, 0, 255, 255}:
+				blue++
+			}
+		}
+		if red != 1 || blue != 0 {
+			t.Errorf("row %d has %d red and %d blue pixels, want 1 and 0", y, red, blue)
+		}
+	}
+
+	// Removing without a width brings the image back to its size.
+	out, err = CarveT(0, 0, nil, remove)(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out.Bounds().Size(); got != image.Pt(12, 8) {
+		t.Errorf("removing a column gave %v, want the original size", got)
+	}
+}
+
+func TestCarveErrors(t *testing.T) {
+	if _, err := CarveT(5, 5, nil, nil)(image.NewRGBA(image.Rect(0, 0, 0, 4))); err == nil {
+		t.Error("carving an empty image should be an error")
+	}
+	mask := image.NewGray(image.Rect(0, 0, 3, 3))
+	if _, err := CarveT(2, 0, mask, nil)(image.NewRGBA(image.Rect(0, 0, 4, 4))); err == nil {
+		t.Error("a mask of the wrong size should be an error")
+	}
+}
+
+func TestCarveArgs(t *testing.T) {
+	tests := []struct {
+		commands string
+		ok       bool
+	}{
+		{"carve,width=10", true},
+		{"carve,height=10", true},
+		{"carve", false},
+		{"carve,width=-1", false},
+		{"carve,width=10,protect=/does/not/exist.png", false},
+		{"carve,scale=2", false},
+	}
+	for _, tt := range tests {
+		_, err := TokensToTfms(SplitCommands(tt.commands), 1)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.commands, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: colors.go
+ * Description:
+ *   Helpers for parsing and comparing colors.
+ */
+
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Colors that can be given by name instead of by hex code.
+var namedColors = map[string]color.NRGBA{
+	"black":       {0, 0, 0, 255},
+	"white":       {255, 255, 255, 255},
+	"red":         {255, 0, 0, 255},
+	"green":       {0, 255, 0, 255},
+	"blue":        {0, 0, 255, 255},
+	"yellow":      {255, 255, 0, 255},
+	"cyan":        {0, 255, 255, 255},
+	"magenta":     {255, 0, 255, 255},
+	"gray":        {128, 128, 128, 255},
+	"transparent": {0, 0, 0, 0},
+}
+
+/*
+ * Parse a color given as a name or as a hex code in the form
+ * #rgb, #rrggbb or #rrggbbaa. The leading # is optional. The color is
+ * returned without premultiplied alpha.
+ */
+func parseColor(s string) (color.NRGBA, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if c, prs := namedColors[s]; prs {
+		return c, nil
+	}
+
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	if len(hex) != 8 {
+		return color.NRGBA{}, fmt.Errorf("%q is not a valid color", s)
+	}
+
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("%q is not a valid color", s)
+	}
+	return color.NRGBA{
+		uint8(value >> 24),
+		uint8(value >> 16),
+		uint8(value >> 8),
+		uint8(value),
+	}, nil
+}
+
+/*
+ * Format a color as a #rrggbb hex code.
+ */
+func hexColor(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+}
+
+/*
+ * Squared euclidean distance between two colors in 8-bit RGB space.
+ */
+func colorDistSq(r0, g0, b0, r1, g1, b1 float64) float64 {
+	dr := r0 - r1
+	dg := g0 - g1
+	db := b0 - b1
+	return dr*dr + dg*dg + db*db
+}
+
+/*
+ * Get the relative luminance of a color as defined by WCAG 2.
+ */
+func relativeLuminance(c color.Color) float64 {
+	r, g, b, _ := c.RGBA()
+	linear := func(v uint32) float64 {
+		s := float64(v>>8) / 255
+		if s <= 0.03928 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*linear(r) + 0.7152*linear(g) + 0.0722*linear(b)
+}
+
+/*
+ * Get the WCAG 2 contrast ratio between two colors, from 1 to 21.
+ */
+func contrastRatio(c0 color.Color, c1 color.Color) float64 {
+	l0 := relativeLuminance(c0)
+	l1 := relativeLuminance(c1)
+	if l0 < l1 {
+		l0, l1 = l1, l0
+	}
+	return (l0 + 0.05) / (l1 + 0.05)
+}
+
+/*
+ * Pick black or white, whichever is easier to read on top of a color.
+ */
+func contrastingForeground(bg color.Color) color.NRGBA {
+	black := namedColors["black"]
+	white := namedColors["white"]
+	if contrastRatio(bg, black) >= contrastRatio(bg, white) {
+		return black
+	}
+	return white
+}
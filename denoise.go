@@ -0,0 +1,287 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: denoise.go
+ * Description:
+ *   Edge-preserving denoising filters: median, bilateral and
+ *   non-local means.
+ */
+
+package main
+
+import (
+	"errors"
+	"image"
+	"math"
+)
+
+/*
+ * Find the median of a histogram holding total values.
+ */
+func histogramMedian(hist *[256]int, total int) uint8 {
+	seen := 0
+	for v := 0; v < 256; v++ {
+		seen += hist[v]
+		if seen*2 > total {
+			return uint8(v)
+		}
+	}
+	return 255
+}
+
+/*
+ * Get a function that will replace every pixel of any image with the
+ * median of the (2*radius+1)^2 window around it, one channel at a time.
+ *
+ * Each row keeps a histogram per channel that slides along with the
+ * window, so moving one pixel only adds and removes a single column.
+ */
+func MedianT(radius int) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		src := toRGBA(img)
+		bounds := src.Bounds()
+		dst := image.NewRGBA(bounds)
+		size := (radius*2 + 1) * (radius*2 + 1)
+
+		pool := GetGlobalWorkers()
+		pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+			var hists [4][256]int
+
+			// Add (sign 1) or remove (sign -1) one column of the window.
+			slide := func(x int, y int, sign int) {
+				px := clamp(x, bounds.Min.X, bounds.Max.X-1)
+				for dy := -radius; dy <= radius; dy++ {
+					py := clamp(y+dy, bounds.Min.Y, bounds.Max.Y-1)
+					i := src.PixOffset(px, py)
+					for c := 0; c < 4; c++ {
+						hists[c][src.Pix[i+c]] += sign
+					}
+				}
+			}
+
+			for y := lo; y < hi; y++ {
+				hists = [4][256]int{}
+				for dx := -radius; dx <= radius; dx++ {
+					slide(bounds.Min.X+dx, y, 1)
+				}
+
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					if x > bounds.Min.X {
+						slide(x-radius-1, y, -1)
+						slide(x+radius, y, 1)
+					}
+					i := dst.PixOffset(x, y)
+					for c := 0; c < 4; c++ {
+						dst.Pix[i+c] = histogramMedian(&hists[c], size)
+					}
+				}
+			}
+		})
+		return dst, nil
+	}
+}
+
+/*
+ * Get a function that will smooth any image while keeping its edges by
+ * weighting every neighbor both by how far away it is and by how different
+ * its color is.
+ */
+func BilateralT(sigmaSpace float64, sigmaColor float64) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		src := toRGBA(img)
+		bounds := src.Bounds()
+		dst := image.NewRGBA(bounds)
+
+		radius := int(math.Ceil(sigmaSpace * 2))
+		size := radius*2 + 1
+		spatial := make([]float64, size*size)
+		for dy := -radius; dy <= radius; dy++ {
+			for dx := -radius; dx <= radius; dx++ {
+				d := float64(dx*dx + dy*dy)
+				spatial[(dy+radius)*size+dx+radius] = math.Exp(-d / (2 * sigmaSpace * sigmaSpace))
+			}
+		}
+
+		// Color weights only depend on the squared distance between colors,
+		// which is at most 3*255^2, so look them up instead of calling Exp.
+		colorWeights := make([]float64, 3*255*255+1)
+		for d := range colorWeights {
+			colorWeights[d] = math.Exp(-float64(d) / (2 * sigmaColor * sigmaColor))
+		}
+
+		pool := GetGlobalWorkers()
+		pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+			for y := lo; y < hi; y++ {
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					center := src.PixOffset(x, y)
+					cr := int(src.Pix[center])
+					cg := int(src.Pix[center+1])
+					cb := int(src.Pix[center+2])
+
+					var r, g, b, a, total float64
+					for dy := -radius; dy <= radius; dy++ {
+						py := clamp(y+dy, bounds.Min.Y, bounds.Max.Y-1)
+						for dx := -radius; dx <= radius; dx++ {
+							px := clamp(x+dx, bounds.Min.X, bounds.Max.X-1)
+							i := src.PixOffset(px, py)
+							nr := int(src.Pix[i])
+							ng := int(src.Pix[i+1])
+							nb := int(src.Pix[i+2])
+
+							d := (nr-cr)*(nr-cr) + (ng-cg)*(ng-cg) + (nb-cb)*(nb-cb)
+							w := spatial[(dy+radius)*size+dx+radius] * colorWeights[d]
+							r += float64(nr) * w
+							g += float64(ng) * w
+							b += float64(nb) * w
+							a += float64(src.Pix[i+3]) * w
+							total += w
+						}
+					}
+
+					// Alpha is averaged with the same weights as the colors,
+					// which are premultiplied, so that they never exceed it.
+					out := dst.PixOffset(x, y)
+					dst.Pix[out] = uint8(math.Round(r / total))
+					dst.Pix[out+1] = uint8(math.Round(g / total))
+					dst.Pix[out+2] = uint8(math.Round(b / total))
+					dst.Pix[out+3] = uint8(math.Round(a / total))
+				}
+			}
+		})
+		return dst, nil
+	}
+}
+
+/*
+ * Get a function that will denoise any image with non-local means: every
+ * pixel becomes an average of the pixels in its search window, weighted by
+ * how similar the patches around them are. h controls how quickly the
+ * weight falls off, and patch and search are odd window sizes.
+ */
+func NLMeansT(h float64, patch int, search int) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		src := toRGBA(img)
+		bounds := src.Bounds()
+		dst := image.NewRGBA(bounds)
+
+		patchRadius := patch / 2
+		searchRadius := search / 2
+		patchPixels := float64(patch * patch * 3)
+
+		// Get a clamped pixel offset, the same way blur handles borders.
+		at := func(x int, y int) int {
+			return src.PixOffset(
+				clamp(x, bounds.Min.X, bounds.Max.X-1),
+				clamp(y, bounds.Min.Y, bounds.Max.Y-1),
+			)
+		}
+
+		pool := GetGlobalWorkers()
+		pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+			for y := lo; y < hi; y++ {
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					var r, g, b, a, total float64
+					for sy := -searchRadius; sy <= searchRadius; sy++ {
+						for sx := -searchRadius; sx <= searchRadius; sx++ {
+							// Mean squared difference between the two patches.
+							var dist float64
+							for py := -patchRadius; py <= patchRadius; py++ {
+								for px := -patchRadius; px <= patchRadius; px++ {
+									i := at(x+px, y+py)
+									j := at(x+sx+px, y+sy+py)
+									for c := 0; c < 3; c++ {
+										d := float64(src.Pix[i+c]) - float64(src.Pix[j+c])
+										dist += d * d
+									}
+								}
+							}
+							w := math.Exp(-dist / patchPixels / (h * h))
+
+							j := at(x+sx, y+sy)
+							r += float64(src.Pix[j]) * w
+							g += float64(src.Pix[j+1]) * w
+							b += float64(src.Pix[j+2]) * w
+							a += float64(src.Pix[j+3]) * w
+							total += w
+						}
+					}
+
+					out := dst.PixOffset(x, y)
+					dst.Pix[out] = uint8(math.Round(r / total))
+					dst.Pix[out+1] = uint8(math.Round(g / total))
+					dst.Pix[out+2] = uint8(math.Round(b / total))
+					dst.Pix[out+3] = uint8(math.Round(a / total))
+				}
+			}
+		})
+		return dst, nil
+	}
+}
+
+/*
+ * Build a median transformation from its command line arguments.
+ */
+func medianFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("radius"); err != nil {
+		return nil, err
+	}
+
+	radius, err := args.getInt("radius", 1)
+	if err != nil {
+		return nil, err
+	}
+	if radius < 1 {
+		return nil, errors.New("radius must be at least 1")
+	}
+	return MedianT(radius), nil
+}
+
+/*
+ * Build a bilateral transformation from its command line arguments.
+ */
+func bilateralFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("sigmaspace", "sigmacolor"); err != nil {
+		return nil, err
+	}
+
+	sigmaSpace, err := args.getFloat("sigmaspace", 3)
+	if err != nil {
+		return nil, err
+	}
+	sigmaColor, err := args.getFloat("sigmacolor", 30)
+	if err != nil {
+		return nil, err
+	}
+	if sigmaSpace <= 0 || sigmaColor <= 0 {
+		return nil, errors.New("sigmas must be greater than 0")
+	}
+	return BilateralT(sigmaSpace, sigmaColor), nil
+}
+
+/*
+ * Build an nlmeans transformation from its command line arguments.
+ */
+func nlmeansFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("h", "patch", "search"); err != nil {
+		return nil, err
+	}
+
+	h, err := args.getFloat("h", 10)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := args.getInt("patch", 3)
+	if err != nil {
+		return nil, err
+	}
+	search, err := args.getInt("search", 11)
+	if err != nil {
+		return nil, err
+	}
+	if h <= 0 {
+		return nil, errors.New("h must be greater than 0")
+	}
+	if patch < 1 || patch%2 == 0 || search < 1 || search%2 == 0 {
+		return nil, errors.New("patch and search must be odd and positive")
+	}
+	return NLMeansT(h, patch, search), nil
+}
@@ -0,0 +1,178 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: denoise_test.go
+ * Description:
+ *   Tests for the median, bilateral and non-local means filters.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestHistogramMedian(t *testing.T) {
+	tests := []struct {
+		values []uint8
+		want   uint8
+	}{
+		{[]uint8{7}, 7},
+		{[]uint8{1, 2, 3}, 2},
+		{[]uint8{9, 0, 0, 9, 9}, 9},
+		{[]uint8{0, 0, 255, 255, 255, 255, 3, 3, 3}, 3},
+	}
+	for _, tt := range tests {
+		var hist [256]int
+		for _, v := range tt.values {
+			hist[v]++
+		}
+		if got := histogramMedian(&hist, len(tt.values)); got != tt.want {
+			t.Errorf("histogramMedian(%v) = %d, want %d", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestMedianMatchesSorting(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	src := image.NewRGBA(image.Rect(1, 2, 14, 11))
+	rng.Read(src.Pix)
+	bounds := src.Bounds()
+
+	for _, radius := range []int{1, 2} {
+		out, err := MedianT(radius)(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst := out.(*image.RGBA)
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				for c := 0; c < 4; c++ {
+					window := make([]int, 0)
+					for dy := -radius; dy <= radius; dy++ {
+						for dx := -radius; dx <= radius; dx++ {
+							px := clamp(x+dx, bounds.Min.X, bounds.Max.X-1)
+							py := clamp(y+dy, bounds.Min.Y, bounds.Max.Y-1)
+							window = append(window, int(src.Pix[src.PixOffset(px, py)+c]))
+						}
+					}
+					sort.Ints(window)
+					want := uint8(window[len(window)/2])
+					if got := dst.Pix[dst.PixOffset(x, y)+c]; got != want {
+						t.Errorf("radius %d: channel %d at (%d, %d) = %d, want %d", radius, c, x, y, got, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+/*
+ * Make an image that is opaque red on the left and fades to transparent
+ * on the right, with a little noise, stored premultiplied.
+ */
+func fadingImage() *image.RGBA {
+	rng := rand.New(rand.NewSource(2))
+	img := image.NewRGBA(image.Rect(0, 0, 16, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			a := 255
+			if x >= 8 {
+				a = 0
+			}
+			if x == 8 {
+				a = 128
+			}
+			noise := uint8(rng.Intn(40))
+			img.Set(x, y, color.NRGBA{200 + noise, noise, 50, uint8(a)})
+		}
+	}
+	return img
+}
+
+func TestSmoothingKeepsPremultipliedColors(t *testing.T) {
+	filters := map[string]func(image.Image) (image.Image, error){
+		"bilateral": BilateralT(2, 60),
+		"nlmeans":   NLMeansT(30, 3, 5),
+	}
+	for name, filter := range filters {
+		out, err := filter(fadingImage())
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst := out.(*image.RGBA)
+		for i := 0; i < len(dst.Pix); i += 4 {
+			a := dst.Pix[i+3]
+			if dst.Pix[i] > a || dst.Pix[i+1] > a || dst.Pix[i+2] > a {
+				t.Fatalf("%s made %v, which has more color than alpha", name, dst.Pix[i:i+4])
+			}
+		}
+		// The solid area stays solid and the clear area stays clear.
+		if a := dst.RGBAAt(1, 4).A; a != 255 {
+			t.Errorf("%s: alpha inside the opaque area is %d", name, a)
+		}
+		if a := dst.RGBAAt(15, 4).A; a != 0 {
+			t.Errorf("%s: alpha inside the clear area is %d", name, a)
+		}
+	}
+}
+
+func TestSmoothingKeepsFlatImages(t *testing.T) {
+	flat := image.NewRGBA(image.Rect(0, 0, 6, 6))
+	for i := range flat.Pix {
+		flat.Pix[i] = []uint8{10, 20, 30, 255}[i%4]
+	}
+	filters := map[string]func(image.Image) (image.Image, error){
+		"median":    MedianT(1),
+		"bilateral": BilateralT(3, 30),
+		"nlmeans":   NLMeansT(10, 3, 11),
+	}
+	for name, filter := range filters {
+		out, err := filter(flat)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := out.(*image.RGBA).Pix; string(got) != string(flat.Pix) {
+			t.Errorf("%s changed a flat image", name)
+		}
+	}
+}
+
+func TestBilateralKeepsEdges(t *testing.T) {
+	img := squareImage(image.Pt(12, 12), image.Rect(6, 0, 12, 12))
+	out, err := BilateralT(3, 10)(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := out.(*image.RGBA)
+	if got := dst.RGBAAt(5, 6).R; got > 2 {
+		t.Errorf("the black side of the edge became %d", got)
+	}
+	if got := dst.RGBAAt(6, 6).R; got < 253 {
+		t.Errorf("the white side of the edge became %d", got)
+	}
+}
+
+func TestDenoiseArgs(t *testing.T) {
+	tests := []struct {
+		commands string
+		ok       bool
+	}{
+		{"median", true},
+		{"median,radius=0", false},
+		{"bilateral,sigmaspace=1,sigmacolor=5", true},
+		{"bilateral,sigmacolor=0", false},
+		{"nlmeans,h=5,patch=5,search=7", true},
+		{"nlmeans,patch=4", false},
+		{"nlmeans,h=0", false},
+	}
+	for _, tt := range tests {
+		_, err := TokensToTfms(SplitCommands(tt.commands), 1)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.commands, err, tt.ok)
+		}
+	}
+}
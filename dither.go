@@ -0,0 +1,319 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: dither.go
+ * Description:
+ *   Reduce an image to a palette with ordered or error diffusion dithering.
+ */
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sync"
+)
+
+/*
+ * One neighbor that receives part of a pixel's quantization error.
+ */
+type diffusionTap struct {
+	dx     int
+	dy     int
+	weight float64
+}
+
+// Error diffusion kernels by method name.
+var DIFFUSION_KERNELS = map[string][]diffusionTap{
+	"floyd-steinberg": {
+		{1, 0, 7 / 16.0},
+		{-1, 1, 3 / 16.0}, {0, 1, 5 / 16.0}, {1, 1, 1 / 16.0},
+	},
+	// Atkinson only spreads 3/4 of the error, which keeps highlights clean.
+	"atkinson": {
+		{1, 0, 1 / 8.0}, {2, 0, 1 / 8.0},
+		{-1, 1, 1 / 8.0}, {0, 1, 1 / 8.0}, {1, 1, 1 / 8.0},
+		{0, 2, 1 / 8.0},
+	},
+}
+
+// Ordered dithering threshold matrix sizes by method name.
+var BAYER_SIZES = map[string]int{
+	"bayer4": 4,
+	"bayer8": 8,
+}
+
+/*
+ * Build an n by n Bayer threshold matrix, where n is a power of two.
+ * Each entry is in [0, 1).
+ */
+func bayerMatrix(n int) [][]float64 {
+	m := [][]int{{0}}
+	for size := 1; size < n; size *= 2 {
+		next := make([][]int, size*2)
+		for y := range next {
+			next[y] = make([]int, size*2)
+		}
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				v := m[y][x] * 4
+				next[y][x] = v
+				next[y][x+size] = v + 2
+				next[y+size][x] = v + 3
+				next[y+size][x+size] = v + 1
+			}
+		}
+		m = next
+	}
+
+	thresholds := make([][]float64, n)
+	for y := range thresholds {
+		thresholds[y] = make([]float64, n)
+		for x := range thresholds[y] {
+			thresholds[y][x] = float64(m[y][x]) / float64(n*n)
+		}
+	}
+	return thresholds
+}
+
+/*
+ * Map every pixel to a palette index with ordered dithering. Every pixel
+ * is independent, so rows are split between workers.
+ */
+func orderedDither(img image.Image, rgb [][3]float64, size int) []uint8 {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	indices := make([]uint8, width*bounds.Dy())
+	matrix := bayerMatrix(size)
+
+	// How far a threshold can push a color. Palettes with more colors have
+	// closer neighbors, so they need less of a push. A 1x1 matrix means no
+	// dithering at all.
+	spread := 255 / math.Cbrt(float64(len(rgb)))
+	if size == 1 {
+		spread = 0
+	}
+
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			row := (y - bounds.Min.Y) * width
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				r, g, b, _ := img.At(x, y).RGBA()
+				offset := spread * (matrix[(y-bounds.Min.Y)%size][(x-bounds.Min.X)%size] - 0.5)
+				indices[row+x-bounds.Min.X] = uint8(nearestColor(
+					rgb,
+					float64(r>>8)+offset,
+					float64(g>>8)+offset,
+					float64(b>>8)+offset,
+				))
+			}
+		}
+	})
+	return indices
+}
+
+/*
+ * Map every pixel to a palette index with error diffusion.
+ *
+ * Each pixel depends on the error of the pixels before it, so the image is
+ * processed as a wavefront: rows are handed out round-robin to goroutines
+ * of its own, and a row may only work on a pixel once the row above it is
+ * far enough ahead that the two rows never touch the same part of the
+ * error buffer. The goroutines wait on each other, so they cannot be pool
+ * tasks, which might not all be running at once.
+ */
+func diffusionDither(img image.Image, rgb [][3]float64, taps []diffusionTap) []uint8 {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	indices := make([]uint8, width*height)
+	if width == 0 || height == 0 {
+		return indices
+	}
+
+	// The working colors, which collect error as pixels are quantized.
+	work := make([]float64, width*height*3)
+
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(0, height, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := 0; x < width; x++ {
+				r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+				i := (y*width + x) * 3
+				work[i] = float64(r >> 8)
+				work[i+1] = float64(g >> 8)
+				work[i+2] = float64(b >> 8)
+			}
+		}
+	})
+
+	// How far ahead the previous row must be: far enough that its writes
+	// into this row land past this row's own forward writes.
+	ahead, behind := 0, 0
+	for _, tap := range taps {
+		if tap.dy == 0 {
+			ahead = max(ahead, tap.dx)
+		} else {
+			behind = max(behind, -tap.dx)
+		}
+	}
+	lag := ahead + behind + 1
+
+	// The number of finished pixels in each row, which is only changed with
+	// mu held. Rows waiting for the row above them sleep on moved.
+	progress := make([]int, height)
+	var mu sync.Mutex
+	moved := sync.NewCond(&mu)
+
+	numTasks := min(pool.NumWorkers, height)
+	var wg sync.WaitGroup
+	wg.Add(numTasks)
+
+	for t := 0; t < numTasks; t++ {
+		go func() {
+			defer wg.Done()
+			for y := t; y < height; y += numTasks {
+				x := 0
+				for x < width {
+					limit := width
+					if y > 0 {
+						mu.Lock()
+						for {
+							done := progress[y-1]
+							limit = width
+							if done < width {
+								limit = done - lag + 1
+							}
+							if limit > x {
+								break
+							}
+							// The row above has not moved far enough yet.
+							moved.Wait()
+						}
+						mu.Unlock()
+					}
+
+					for ; x < limit; x++ {
+						i := (y*width + x) * 3
+						r := math.Min(255, math.Max(0, work[i]))
+						g := math.Min(255, math.Max(0, work[i+1]))
+						b := math.Min(255, math.Max(0, work[i+2]))
+
+						index := nearestColor(rgb, r, g, b)
+						indices[y*width+x] = uint8(index)
+
+						errR := r - rgb[index][0]
+						errG := g - rgb[index][1]
+						errB := b - rgb[index][2]
+						for _, tap := range taps {
+							nx, ny := x+tap.dx, y+tap.dy
+							if nx < 0 || nx >= width || ny >= height {
+								continue
+							}
+							j := (ny*width + nx) * 3
+							work[j] += errR * tap.weight
+							work[j+1] += errG * tap.weight
+							work[j+2] += errB * tap.weight
+						}
+					}
+					mu.Lock()
+					progress[y] = x
+					mu.Unlock()
+					moved.Broadcast()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return indices
+}
+
+/*
+ * Map every pixel of an image to an index into pal using the given
+ * dithering method. An empty method maps each pixel to its nearest color.
+ */
+func ditherIndices(img image.Image, pal color.Palette, method string) ([]uint8, error) {
+	rgb, err := paletteRGB(pal)
+	if err != nil {
+		return nil, err
+	}
+	if len(rgb) > MAX_PALETTE_SIZE {
+		return nil, fmt.Errorf("palette has more than %d colors", MAX_PALETTE_SIZE)
+	}
+
+	if method == "" || method == "none" {
+		return orderedDither(img, rgb, 1), nil
+	}
+	if size, prs := BAYER_SIZES[method]; prs {
+		return orderedDither(img, rgb, size), nil
+	}
+	if taps, prs := DIFFUSION_KERNELS[method]; prs {
+		return diffusionDither(img, rgb, taps), nil
+	}
+	return nil, fmt.Errorf("%s is not a valid dithering method", method)
+}
+
+/*
+ * Get a function that will dither any image to a palette. The alpha
+ * channel of the image is kept as is.
+ */
+func DitherT(method string, source paletteSource) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		pal := source(img)
+		indices, err := ditherIndices(img, pal, method)
+		if err != nil {
+			return nil, err
+		}
+
+		colors := make([]color.NRGBA, len(pal))
+		for i, c := range pal {
+			colors[i] = color.NRGBAModel.Convert(c).(color.NRGBA)
+		}
+
+		bounds := img.Bounds()
+		width := bounds.Dx()
+		dithered := image.NewRGBA(bounds)
+
+		pool := GetGlobalWorkers()
+		pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+			for y := lo; y < hi; y++ {
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					_, _, _, a := img.At(x, y).RGBA()
+					c := colors[indices[(y-bounds.Min.Y)*width+x-bounds.Min.X]]
+					c.A = uint8(a >> 8)
+					dithered.Set(x, y, c)
+				}
+			}
+		})
+		return dithered, nil
+	}
+}
+
+/*
+ * Build a dither transformation from its command line arguments.
+ */
+func ditherFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("method", "palette", "colors"); err != nil {
+		return nil, err
+	}
+
+	method := args.getString("method", "floyd-steinberg")
+	_, isOrdered := BAYER_SIZES[method]
+	_, isDiffusion := DIFFUSION_KERNELS[method]
+	if !isOrdered && !isDiffusion && method != "none" {
+		return nil, fmt.Errorf("%s is not a valid dithering method", method)
+	}
+
+	numColors, err := args.getInt("colors", 16)
+	if err != nil {
+		return nil, err
+	}
+	source, err := parsePalette(args.getString("palette", "1bit"), numColors)
+	if err != nil {
+		return nil, err
+	}
+
+	return DitherT(method, source), nil
+}
@@ -0,0 +1,192 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: dither_test.go
+ * Description:
+ *   Tests for ordered and error diffusion dithering.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"reflect"
+	"testing"
+)
+
+/*
+ * Make an opaque image whose colors change smoothly across and down, so
+ * that dithering has plenty of in-between colors to work on.
+ */
+func gradientImage(width int, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, color.RGBA{
+				uint8(x * 255 / max(1, width-1)),
+				uint8(y * 255 / max(1, height-1)),
+				uint8((x + y) * 255 / max(1, width+height-2)),
+				255,
+			})
+		}
+	}
+	return img
+}
+
+/*
+ * Error diffusion done one pixel at a time, to check the wavefront against.
+ */
+func sequentialDiffusion(img image.Image, rgb [][3]float64, taps []diffusionTap) []uint8 {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	work := make([]float64, width*height*3)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			i := (y*width + x) * 3
+			work[i], work[i+1], work[i+2] = float64(r>>8), float64(g>>8), float64(b>>8)
+		}
+	}
+
+	indices := make([]uint8, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := (y*width + x) * 3
+			r := math.Min(255, math.Max(0, work[i]))
+			g := math.Min(255, math.Max(0, work[i+1]))
+			b := math.Min(255, math.Max(0, work[i+2]))
+			index := nearestColor(rgb, r, g, b)
+			indices[y*width+x] = uint8(index)
+			for _, tap := range taps {
+				nx, ny := x+tap.dx, y+tap.dy
+				if nx < 0 || nx >= width || ny >= height {
+					continue
+				}
+				j := (ny*width + nx) * 3
+				work[j] += (r - rgb[index][0]) * tap.weight
+				work[j+1] += (g - rgb[index][1]) * tap.weight
+				work[j+2] += (b - rgb[index][2]) * tap.weight
+			}
+		}
+	}
+	return indices
+}
+
+func TestBayerMatrix(t *testing.T) {
+	tests := []struct {
+		n    int
+		want [][]int
+	}{
+		{1, [][]int{{0}}},
+		{2, [][]int{{0, 2}, {3, 1}}},
+		{4, [][]int{
+			{0, 8, 2, 10},
+			{12, 4, 14, 6},
+			{3, 11, 1, 9},
+			{15, 7, 13, 5},
+		}},
+	}
+	for _, tt := range tests {
+		got := bayerMatrix(tt.n)
+		for y, row := range tt.want {
+			for x, v := range row {
+				want := float64(v) / float64(tt.n*tt.n)
+				if got[y][x] != want {
+					t.Errorf("bayerMatrix(%d)[%d][%d] = %v, want %v", tt.n, y, x, got[y][x], want)
+				}
+			}
+		}
+	}
+}
+
+func TestBayerMatrixUsesEveryThresholdOnce(t *testing.T) {
+	m := bayerMatrix(8)
+	seen := make(map[float64]bool)
+	for _, row := range m {
+		for _, v := range row {
+			if v < 0 || v >= 1 || seen[v] {
+				t.Fatalf("bayerMatrix(8) has %v more than once or out of [0, 1)", v)
+			}
+			seen[v] = true
+		}
+	}
+	if len(seen) != 64 {
+		t.Errorf("bayerMatrix(8) has %d thresholds, want 64", len(seen))
+	}
+}
+
+func TestDiffusionDitherMatchesSequential(t *testing.T) {
+	rgb, err := paletteRGB(PALETTE_PRESETS["cga"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sizes := []image.Point{{1, 1}, {1, 9}, {9, 1}, {3, 40}, {61, 37}}
+	for method, taps := range DIFFUSION_KERNELS {
+		for _, size := range sizes {
+			img := gradientImage(size.X, size.Y)
+			got := diffusionDither(img, rgb, taps)
+			want := sequentialDiffusion(img, rgb, taps)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s on %v differs from dithering one pixel at a time", method, size)
+			}
+		}
+	}
+}
+
+func TestDitherIndicesNoneIsNearestColor(t *testing.T) {
+	pal := PALETTE_PRESETS["1bit"]
+	img := image.NewGray(image.Rect(0, 0, 4, 1))
+	for x, v := range []uint8{0, 100, 140, 255} {
+		img.SetGray(x, 0, color.Gray{v})
+	}
+	got, err := ditherIndices(img, pal, "none")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint8{0, 0, 1, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ditherIndices(none) = %v, want %v", got, want)
+	}
+}
+
+func TestDitherFromArgs(t *testing.T) {
+	tests := []struct {
+		commands string
+		ok       bool
+	}{
+		{"dither", true},
+		{"dither,method=none", true},
+		{"dither,method=bayer4,palette=gameboy", true},
+		{"dither,method=atkinson,palette=#000:#fff", true},
+		{"dither,palette=auto,colors=8", true},
+		{"dither,method=sierra", false},
+		{"dither,palette=auto,colors=1", false},
+		{"dither,palette=#000", false},
+		{"dither,size=3", false},
+	}
+	for _, tt := range tests {
+		_, err := TokensToTfms(SplitCommands(tt.commands), 1)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.commands, err, tt.ok)
+		}
+	}
+}
+
+func TestDitherKeepsAlpha(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{255, 255, 255, 255})
+	img.SetNRGBA(1, 0, color.NRGBA{255, 255, 255, 64})
+	source, err := parsePalette("1bit", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := DitherT("floyd-steinberg", source)(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for x, want := range []uint16{0xffff, 64 * 0x101} {
+		if _, _, _, a := out.At(x, 0).RGBA(); a != uint32(want) {
+			t.Errorf("alpha at %d = %#x, want %#x", x, a, want)
+		}
+	}
+}
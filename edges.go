@@ -0,0 +1,373 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: edges.go
+ * Description:
+ *   Edge detection with gradient operators and the Canny edge detector.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Horizontal and vertical gradient kernels by operator name.
+var EDGE_KERNELS = map[string][2][][]float64{
+	"sobel": {
+		{{-1, 0, 1}, {-2, 0, 2}, {-1, 0, 1}},
+		{{-1, -2, -1}, {0, 0, 0}, {1, 2, 1}},
+	},
+	"prewitt": {
+		{{-1, 0, 1}, {-1, 0, 1}, {-1, 0, 1}},
+		{{-1, -1, -1}, {0, 0, 0}, {1, 1, 1}},
+	},
+	"scharr": {
+		{{-3, 0, 3}, {-10, 0, 10}, {-3, 0, 3}},
+		{{-3, -10, -3}, {0, 0, 0}, {3, 10, 3}},
+	},
+}
+
+// Pixel states used by Canny's hysteresis.
+const (
+	EDGE_NONE   = 0
+	EDGE_WEAK   = 1
+	EDGE_STRONG = 2
+)
+
+/*
+ * The gradient of every pixel of an image, stored row by row.
+ */
+type gradientField struct {
+	bounds image.Rectangle
+	// Gradient magnitudes, scaled so that a full black to white step is 255.
+	mag []float64
+	// Gradient directions in radians, from -Pi to Pi.
+	dir []float64
+}
+
+/*
+ * Compute the gradient of an image's grayscale version with a named
+ * operator. Rows are split between workers.
+ */
+func computeGradients(img image.Image, method string) (gradientField, error) {
+	kernels, prs := EDGE_KERNELS[method]
+	if !prs {
+		return gradientField{}, fmt.Errorf("%s is not a valid edge operator", method)
+	}
+
+	// Scale by the positive weights so every operator gives the same range.
+	var norm float64
+	for _, row := range kernels[0] {
+		for _, w := range row {
+			norm += math.Max(0, w)
+		}
+	}
+
+	gray, err := GrayscaleParallel(img)
+	if err != nil {
+		return gradientField{}, err
+	}
+
+	bounds := gray.Bounds()
+	width := bounds.Dx()
+	field := gradientField{
+		bounds: bounds,
+		mag:    make([]float64, width*bounds.Dy()),
+		dir:    make([]float64, width*bounds.Dy()),
+	}
+
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				gx, _, _, _ := convolvePixel(gray, kernels[0], bounds, x, y)
+				gy, _, _, _ := convolvePixel(gray, kernels[1], bounds, x, y)
+				i := (y-bounds.Min.Y)*width + x - bounds.Min.X
+				field.mag[i] = math.Hypot(gx, gy) / norm
+				field.dir[i] = math.Atan2(gy, gx)
+			}
+		}
+	})
+	return field, nil
+}
+
+/*
+ * Convert a hue in [0, 360) with the given brightness into an RGB color.
+ */
+func hueColor(hue float64, value float64) color.RGBA {
+	h := hue / 60
+	f := h - math.Floor(h)
+	v := uint8(value)
+	p := uint8(0)
+	q := uint8(value * (1 - f))
+	t := uint8(value * f)
+
+	switch int(h) % 6 {
+	case 0:
+		return color.RGBA{v, t, p, 255}
+	case 1:
+		return color.RGBA{q, v, p, 255}
+	case 2:
+		return color.RGBA{p, v, t, 255}
+	case 3:
+		return color.RGBA{p, q, v, 255}
+	case 4:
+		return color.RGBA{t, p, v, 255}
+	default:
+		return color.RGBA{v, p, q, 255}
+	}
+}
+
+/*
+ * Get a function that will turn any image into an edge map with the given
+ * operator. The edge map is grayscale, unless direction is set, in which
+ * case the hue of each pixel shows which way its gradient points.
+ */
+func EdgesT(method string, direction bool) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		field, err := computeGradients(img, method)
+		if err != nil {
+			return nil, err
+		}
+
+		bounds := field.bounds
+		width := bounds.Dx()
+		var out draw.Image
+		if direction {
+			out = image.NewRGBA(bounds)
+		} else {
+			out = image.NewGray(bounds)
+		}
+
+		pool := GetGlobalWorkers()
+		pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+			for y := lo; y < hi; y++ {
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					i := (y-bounds.Min.Y)*width + x - bounds.Min.X
+					mag := math.Min(255, field.mag[i])
+					if direction {
+						hue := (field.dir[i] + math.Pi) * 180 / math.Pi
+						out.Set(x, y, hueColor(math.Mod(hue, 360), mag))
+					} else {
+						out.Set(x, y, color.Gray{uint8(mag)})
+					}
+				}
+			}
+		})
+		return out, nil
+	}
+}
+
+/*
+ * Thin edges down to one pixel by keeping only pixels whose magnitude is
+ * the largest along their gradient direction, and sort the survivors into
+ * weak and strong edges.
+ */
+func suppressNonMax(field gradientField, low float64, high float64) []uint8 {
+	bounds := field.bounds
+	width, height := bounds.Dx(), bounds.Dy()
+	states := make([]uint8, width*height)
+
+	magAt := func(x int, y int) float64 {
+		if x < 0 || x >= width || y < 0 || y >= height {
+			return 0
+		}
+		return field.mag[y*width+x]
+	}
+
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(0, height, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := 0; x < width; x++ {
+				i := y*width + x
+				mag := field.mag[i]
+				if mag < low {
+					continue
+				}
+
+				// Round the direction to one of four neighbor pairs.
+				angle := math.Mod(field.dir[i]*180/math.Pi+180, 180)
+				var dx, dy int
+				switch {
+				case angle < 22.5 || angle >= 157.5:
+					dx, dy = 1, 0
+				case angle < 67.5:
+					dx, dy = 1, 1
+				case angle < 112.5:
+					dx, dy = 0, 1
+				default:
+					dx, dy = -1, 1
+				}
+				if mag < magAt(x+dx, y+dy) || mag < magAt(x-dx, y-dy) {
+					continue
+				}
+
+				if mag >= high {
+					states[i] = EDGE_STRONG
+				} else {
+					states[i] = EDGE_WEAK
+				}
+			}
+		}
+	})
+	return states
+}
+
+/*
+ * Turn weak edges into strong edges when they touch a strong edge.
+ *
+ * Every strip of rows is flood filled on its own in parallel. Then the rows
+ * where two strips meet are checked, and if an edge crossed a boundary the
+ * strips are filled again, until nothing changes.
+ */
+func hysteresis(states []uint8, width int, height int) {
+	pool := GetGlobalWorkers()
+
+	promote := func(x int, y int) bool {
+		if x < 0 || x >= width || states[y*width+x] != EDGE_WEAK {
+			return false
+		}
+		states[y*width+x] = EDGE_STRONG
+		return true
+	}
+
+	for {
+		var mu sync.Mutex
+		starts := make([]int, 0)
+
+		pool.ParallelFor(0, height, func(lo int, hi int) {
+			mu.Lock()
+			starts = append(starts, lo)
+			mu.Unlock()
+
+			stack := make([]int, 0)
+			for i := lo * width; i < hi*width; i++ {
+				if states[i] == EDGE_STRONG {
+					stack = append(stack, i)
+				}
+			}
+			for len(stack) > 0 {
+				i := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				x, y := i%width, i/width
+				for ny := max(lo, y-1); ny <= min(hi-1, y+1); ny++ {
+					for nx := x - 1; nx <= x+1; nx++ {
+						if promote(nx, ny) {
+							stack = append(stack, ny*width+nx)
+						}
+					}
+				}
+			}
+		})
+
+		changed := false
+		sort.Ints(starts)
+		for _, y := range starts[min(1, len(starts)):] {
+			for x := 0; x < width; x++ {
+				for dx := -1; dx <= 1; dx++ {
+					if states[y*width+x] == EDGE_STRONG && promote(x+dx, y-1) {
+						changed = true
+					}
+					if states[(y-1)*width+x] == EDGE_STRONG && promote(x+dx, y) {
+						changed = true
+					}
+				}
+			}
+		}
+		if !changed {
+			return
+		}
+	}
+}
+
+/*
+ * Get a function that will find the edges of any image with the Canny
+ * edge detector: blur with the given sigma, take Sobel gradients, thin the
+ * edges and keep weak edges only when they are connected to strong ones.
+ */
+func CannyT(low float64, high float64, sigma float64) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		var blurred image.Image = img
+		if sigma > 0 {
+			blurred = convolveParallel(img, gaussianKernel(sigma))
+		}
+
+		field, err := computeGradients(blurred, "sobel")
+		if err != nil {
+			return nil, err
+		}
+
+		bounds := field.bounds
+		width, height := bounds.Dx(), bounds.Dy()
+		states := suppressNonMax(field, low, high)
+		hysteresis(states, width, height)
+
+		edges := image.NewGray(bounds)
+		pool := GetGlobalWorkers()
+		pool.ParallelFor(0, height, func(lo int, hi int) {
+			for y := lo; y < hi; y++ {
+				for x := 0; x < width; x++ {
+					if states[y*width+x] == EDGE_STRONG {
+						edges.Pix[edges.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)] = 255
+					}
+				}
+			}
+		})
+		return edges, nil
+	}
+}
+
+/*
+ * Build an edges transformation from its command line arguments.
+ */
+func edgesFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("method", "direction"); err != nil {
+		return nil, err
+	}
+
+	method := args.getString("method", "sobel")
+	if _, prs := EDGE_KERNELS[method]; !prs {
+		return nil, fmt.Errorf("%s is not a valid edge operator", method)
+	}
+	direction, err := args.getBool("direction", false)
+	if err != nil {
+		return nil, err
+	}
+	return EdgesT(method, direction), nil
+}
+
+/*
+ * Build a canny transformation from its command line arguments.
+ */
+func cannyFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("low", "high", "sigma"); err != nil {
+		return nil, err
+	}
+
+	low, err := args.getFloat("low", 20)
+	if err != nil {
+		return nil, err
+	}
+	high, err := args.getFloat("high", 50)
+	if err != nil {
+		return nil, err
+	}
+	sigma, err := args.getFloat("sigma", 1.4)
+	if err != nil {
+		return nil, err
+	}
+	if low < 0 || high < low {
+		return nil, errors.New("thresholds must satisfy 0 <= low <= high")
+	}
+	if sigma < 0 {
+		return nil, errors.New("sigma must not be negative")
+	}
+	return CannyT(low, high, sigma), nil
+}
@@ -0,0 +1,164 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: edges_test.go
+ * Description:
+ *   Tests for gradient operators and the Canny edge detector.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+/*
+ * Make a black image with a white rectangle on it.
+ */
+func squareImage(size image.Point, square image.Rectangle) *image.RGBA {
+	img := image.NewRGBA(image.Rectangle{Max: size})
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	draw.Draw(img, square, image.NewUniform(color.White), image.Point{}, draw.Src)
+	return img
+}
+
+func TestComputeGradientsStep(t *testing.T) {
+	// Black on the left half and white on the right.
+	img := squareImage(image.Pt(8, 5), image.Rect(4, 0, 8, 5))
+	for method := range EDGE_KERNELS {
+		field, err := computeGradients(img, method)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for x := 0; x < 8; x++ {
+			i := 2*8 + x
+			want := 0.0
+			if x == 3 || x == 4 {
+				want = 255
+			}
+			if math.Abs(field.mag[i]-want) > 1e-9 {
+				t.Errorf("%s: magnitude at x=%d is %v, want %v", method, x, field.mag[i], want)
+			}
+			if want > 0 && field.dir[i] != 0 {
+				t.Errorf("%s: direction at x=%d is %v, want 0", method, x, field.dir[i])
+			}
+		}
+	}
+	if _, err := computeGradients(img, "roberts"); err == nil {
+		t.Error("roberts should not be a valid operator")
+	}
+}
+
+func TestHueColor(t *testing.T) {
+	tests := []struct {
+		hue  float64
+		want color.RGBA
+	}{
+		{0, color.RGBA{255, 0, 0, 255}},
+		{60, color.RGBA{255, 255, 0, 255}},
+		{120, color.RGBA{0, 255, 0, 255}},
+		{180, color.RGBA{0, 255, 255, 255}},
+		{240, color.RGBA{0, 0, 255, 255}},
+		{300, color.RGBA{255, 0, 255, 255}},
+	}
+	for _, tt := range tests {
+		if got := hueColor(tt.hue, 255); got != tt.want {
+			t.Errorf("hueColor(%v) = %v, want %v", tt.hue, got, tt.want)
+		}
+	}
+}
+
+func TestHysteresisMatchesFloodFill(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, size := range []image.Point{{1, 1}, {7, 3}, {13, 29}, {40, 40}} {
+		states := make([]uint8, size.X*size.Y)
+		for i := range states {
+			// Mostly weak, so that chains cross between strips of rows.
+			switch v := rng.Intn(10); {
+			case v == 0:
+				states[i] = EDGE_STRONG
+			case v < 7:
+				states[i] = EDGE_WEAK
+			}
+		}
+
+		want := append([]uint8(nil), states...)
+		stack := make([]int, 0)
+		for i, s := range want {
+			if s == EDGE_STRONG {
+				stack = append(stack, i)
+			}
+		}
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y := i%size.X, i/size.X
+			for ny := max(0, y-1); ny <= min(size.Y-1, y+1); ny++ {
+				for nx := max(0, x-1); nx <= min(size.X-1, x+1); nx++ {
+					if want[ny*size.X+nx] == EDGE_WEAK {
+						want[ny*size.X+nx] = EDGE_STRONG
+						stack = append(stack, ny*size.X+nx)
+					}
+				}
+			}
+		}
+
+		hysteresis(states, size.X, size.Y)
+		if !reflect.DeepEqual(states, want) {
+			t.Errorf("hysteresis on %v differs from a flood fill", size)
+		}
+	}
+}
+
+func TestCannyFindsOutline(t *testing.T) {
+	square := image.Rect(10, 10, 30, 30)
+	out, err := CannyT(20, 50, 1)(squareImage(image.Pt(40, 40), square))
+	if err != nil {
+		t.Fatal(err)
+	}
+	edges := out.(*image.Gray)
+	near := square.Inset(-2)
+	inside := square.Inset(3)
+	found := 0
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 40; x++ {
+			on := edges.GrayAt(x, y).Y == 255
+			p := image.Pt(x, y)
+			if on && (!p.In(near) || p.In(inside)) {
+				t.Errorf("edge at %v, away from the outline", p)
+			}
+			if on {
+				found++
+			}
+		}
+	}
+	if found < 4*square.Dx() {
+		t.Errorf("found %d edge pixels, want the whole outline", found)
+	}
+}
+
+func TestEdgeArgs(t *testing.T) {
+	tests := []struct {
+		commands string
+		ok       bool
+	}{
+		{"edges", true},
+		{"edges,method=scharr,direction=true", true},
+		{"edges,method=laplace", false},
+		{"canny", true},
+		{"canny,low=10,high=10,sigma=0", true},
+		{"canny,low=30,high=20", false},
+		{"canny,sigma=-1", false},
+	}
+	for _, tt := range tests {
+		_, err := TokensToTfms(SplitCommands(tt.commands), 1)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.commands, err, tt.ok)
+		}
+	}
+}
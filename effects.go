@@ -0,0 +1,304 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: effects.go
+ * Description:
+ *   Photographic effects: vignettes, film grain and light leaks.
+ */
+
+package main
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"math"
+)
+
+/*
+ * A seeded noise generator. Every value only depends on the seed and the
+ * position it is asked for, so the output is the same no matter how the
+ * image is split between workers.
+ */
+type noiseSource struct {
+	seed uint64
+}
+
+/*
+ * Mix bits with the SplitMix64 finalizer.
+ */
+func mix64(z uint64) uint64 {
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+/*
+ * Get a value in [-1, 1] for a lattice point and channel.
+ */
+func (n noiseSource) at(x int, y int, channel int) float64 {
+	h := mix64(n.seed ^ mix64(uint64(int64(x))*0x9e3779b97f4a7c15^uint64(int64(y))<<21^uint64(channel)<<42))
+	// Average two uniform values so the grain is closer to Gaussian.
+	a := float64(h>>40) / float64(1<<24)
+	b := float64(h&0xffffff) / float64(1<<24)
+	return a + b - 1
+}
+
+/*
+ * Get smoothly interpolated noise at a point, with lattice points size
+ * pixels apart.
+ */
+func (n noiseSource) smooth(x float64, y float64, size float64, channel int) float64 {
+	fx, fy := x/size, y/size
+	x0, y0 := math.Floor(fx), math.Floor(fy)
+	tx, ty := fx-x0, fy-y0
+	ix, iy := int(x0), int(y0)
+
+	top := n.at(ix, iy, channel)*(1-tx) + n.at(ix+1, iy, channel)*tx
+	bot := n.at(ix, iy+1, channel)*(1-tx) + n.at(ix+1, iy+1, channel)*tx
+	return top*(1-ty) + bot*ty
+}
+
+/*
+ * Ease from 0 to 1 as v goes from edge0 to edge1.
+ */
+func smoothstep(edge0 float64, edge1 float64, v float64) float64 {
+	if edge1 <= edge0 {
+		if v < edge0 {
+			return 0
+		}
+		return 1
+	}
+	t := math.Min(1, math.Max(0, (v-edge0)/(edge1-edge0)))
+	return t * t * (3 - 2*t)
+}
+
+/*
+ * Get a function that will fade the edges of any image towards a color.
+ *
+ * Distances are measured from the center so that a corner is at 1. The
+ * fade starts at radius, reaches its full strength after another feather,
+ * and is a circle unless elliptical is set, in which case it follows the
+ * aspect ratio of the image.
+ */
+func VignetteT(strength float64, radius float64, feather float64, clr color.NRGBA, elliptical bool) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		dst := copyRGBA(img)
+		bounds := dst.Bounds()
+		cx := float64(bounds.Min.X+bounds.Max.X) / 2
+		cy := float64(bounds.Min.Y+bounds.Max.Y) / 2
+		halfW := float64(bounds.Dx()) / 2
+		halfH := float64(bounds.Dy()) / 2
+		halfDiag := math.Hypot(halfW, halfH)
+
+		pool := GetGlobalWorkers()
+		pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+			for y := lo; y < hi; y++ {
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					dx := float64(x) + 0.5 - cx
+					dy := float64(y) + 0.5 - cy
+					var d float64
+					if elliptical {
+						d = math.Hypot(dx/halfW, dy/halfH) / math.Sqrt2
+					} else {
+						d = math.Hypot(dx, dy) / halfDiag
+					}
+
+					t := smoothstep(radius, radius+feather, d) * strength * float64(clr.A) / 255
+					i := dst.PixOffset(x, y)
+					a := float64(dst.Pix[i+3]) / 255
+					// Pix is premultiplied, so the color is scaled by alpha too.
+					dst.Pix[i] = uint8(float64(dst.Pix[i])*(1-t) + float64(clr.R)*a*t)
+					dst.Pix[i+1] = uint8(float64(dst.Pix[i+1])*(1-t) + float64(clr.G)*a*t)
+					dst.Pix[i+2] = uint8(float64(dst.Pix[i+2])*(1-t) + float64(clr.B)*a*t)
+				}
+			}
+		})
+		return dst, nil
+	}
+}
+
+/*
+ * Get a function that will add film grain to any image. amount is the
+ * largest change in brightness from 0 to 255, size is how many pixels
+ * across a grain is, and monochrome grain changes all channels together.
+ * The same seed always gives the same grain.
+ */
+func GrainT(amount float64, size float64, monochrome bool, seed uint64) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		dst := copyRGBA(img)
+		bounds := dst.Bounds()
+		noise := noiseSource{seed}
+
+		pool := GetGlobalWorkers()
+		pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+			for y := lo; y < hi; y++ {
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					i := dst.PixOffset(x, y)
+					a := float64(dst.Pix[i+3]) / 255
+					for c := 0; c < 3; c++ {
+						channel := c
+						if monochrome {
+							channel = 0
+						}
+						var n float64
+						if size <= 1 {
+							n = noise.at(x, y, channel)
+						} else {
+							n = noise.smooth(float64(x), float64(y), size, channel)
+						}
+						v := float64(dst.Pix[i+c]) + n*amount*a
+						dst.Pix[i+c] = uint8(math.Min(255*a, math.Max(0, v)))
+					}
+				}
+			}
+		})
+		return dst, nil
+	}
+}
+
+/*
+ * Get a function that will add a light leak to any image: a soft glow of
+ * color that is screened over the image, centered at (x, y) given as
+ * fractions of the width and height.
+ */
+func LightLeakT(clr color.NRGBA, x float64, y float64, radius float64, strength float64) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		dst := copyRGBA(img)
+		bounds := dst.Bounds()
+		cx := float64(bounds.Min.X) + x*float64(bounds.Dx())
+		cy := float64(bounds.Min.Y) + y*float64(bounds.Dy())
+		reach := radius * math.Hypot(float64(bounds.Dx()), float64(bounds.Dy()))
+		leak := [3]float64{float64(clr.R) / 255, float64(clr.G) / 255, float64(clr.B) / 255}
+
+		pool := GetGlobalWorkers()
+		pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+			for py := lo; py < hi; py++ {
+				for px := bounds.Min.X; px < bounds.Max.X; px++ {
+					d := math.Hypot(float64(px)+0.5-cx, float64(py)+0.5-cy) / reach
+					t := (1 - smoothstep(0, 1, d)) * strength
+					if t <= 0 {
+						continue
+					}
+
+					i := dst.PixOffset(px, py)
+					a := float64(dst.Pix[i+3]) / 255
+					for c := 0; c < 3; c++ {
+						// Screen: 1 - (1 - base) * (1 - leak).
+						base := float64(dst.Pix[i+c]) / 255
+						screened := a - (a-base)*(1-leak[c]*t)
+						dst.Pix[i+c] = uint8(math.Round(screened * 255))
+					}
+				}
+			}
+		})
+		return dst, nil
+	}
+}
+
+/*
+ * Build a vignette transformation from its command line arguments.
+ */
+func vignetteFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("strength", "radius", "feather", "color", "elliptical"); err != nil {
+		return nil, err
+	}
+
+	strength, err := args.getFloat("strength", 0.6)
+	if err != nil {
+		return nil, err
+	}
+	radius, err := args.getFloat("radius", 0.5)
+	if err != nil {
+		return nil, err
+	}
+	feather, err := args.getFloat("feather", 0.5)
+	if err != nil {
+		return nil, err
+	}
+	clr, err := parseColor(args.getString("color", "black"))
+	if err != nil {
+		return nil, err
+	}
+	elliptical, err := args.getBool("elliptical", false)
+	if err != nil {
+		return nil, err
+	}
+	if strength < 0 || strength > 1 {
+		return nil, errors.New("strength must be between 0 and 1")
+	}
+	if radius < 0 || feather < 0 {
+		return nil, errors.New("radius and feather must not be negative")
+	}
+	return VignetteT(strength, radius, feather, clr, elliptical), nil
+}
+
+/*
+ * Build a grain transformation from its command line arguments. The seed
+ * is used unless another one is given.
+ */
+func grainFromArgs(args tfmArgs, seed int64) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("amount", "size", "monochrome", "seed"); err != nil {
+		return nil, err
+	}
+
+	amount, err := args.getFloat("amount", 20)
+	if err != nil {
+		return nil, err
+	}
+	size, err := args.getFloat("size", 1)
+	if err != nil {
+		return nil, err
+	}
+	monochrome, err := args.getBool("monochrome", true)
+	if err != nil {
+		return nil, err
+	}
+	seed, err = args.getSeed(seed)
+	if err != nil {
+		return nil, err
+	}
+	if amount < 0 || amount > 255 {
+		return nil, errors.New("amount must be between 0 and 255")
+	}
+	if size <= 0 {
+		return nil, errors.New("size must be greater than 0")
+	}
+	return GrainT(amount, size, monochrome, uint64(seed)), nil
+}
+
+/*
+ * Build a lightleak transformation from its command line arguments.
+ */
+func lightLeakFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("color", "x", "y", "radius", "strength"); err != nil {
+		return nil, err
+	}
+
+	clr, err := parseColor(args.getString("color", "#ff7a30"))
+	if err != nil {
+		return nil, err
+	}
+	x, err := args.getFloat("x", 0)
+	if err != nil {
+		return nil, err
+	}
+	y, err := args.getFloat("y", 0)
+	if err != nil {
+		return nil, err
+	}
+	radius, err := args.getFloat("radius", 0.6)
+	if err != nil {
+		return nil, err
+	}
+	strength, err := args.getFloat("strength", 0.7)
+	if err != nil {
+		return nil, err
+	}
+	if radius <= 0 {
+		return nil, errors.New("radius must be greater than 0")
+	}
+	if strength < 0 || strength > 1 {
+		return nil, errors.New("strength must be between 0 and 1")
+	}
+	return LightLeakT(clr, x, y, radius, strength), nil
+}
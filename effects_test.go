@@ -0,0 +1,213 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: effects_test.go
+ * Description:
+ *   Tests for vignettes, film grain and light leaks.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+/*
+ * Make an opaque image filled with one color.
+ */
+func flatImage(w int, h int, clr color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(clr), image.Point{}, draw.Src)
+	return img
+}
+
+func TestMix64(t *testing.T) {
+	tests := []struct {
+		z, want uint64
+	}{
+		{0, 0},
+		// The first output of SplitMix64 seeded with 0.
+		{0x9e3779b97f4a7c15, 0xe220a8397b1dcdaf},
+	}
+	for _, tt := range tests {
+		if got := mix64(tt.z); got != tt.want {
+			t.Errorf("mix64(%#x) = %#x, want %#x", tt.z, got, tt.want)
+		}
+	}
+}
+
+func TestSmoothstep(t *testing.T) {
+	tests := []struct {
+		edge0, edge1, v float64
+		want            float64
+	}{
+		{0, 1, -1, 0},
+		{0, 1, 0, 0},
+		{0, 1, 0.5, 0.5},
+		{0, 1, 1, 1},
+		{0, 1, 3, 1},
+		{2, 4, 3, 0.5},
+		{0, 1, 0.25, 0.15625},
+		{1, 1, 0.5, 0},
+		{1, 1, 1, 1},
+	}
+	for _, tt := range tests {
+		if got := smoothstep(tt.edge0, tt.edge1, tt.v); got != tt.want {
+			t.Errorf("smoothstep(%v, %v, %v) = %v, want %v", tt.edge0, tt.edge1, tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestNoiseRange(t *testing.T) {
+	noise := noiseSource{7}
+	var sum float64
+	for y := -20; y < 20; y++ {
+		for x := -20; x < 20; x++ {
+			v := noise.at(x, y, 0)
+			if v < -1 || v > 1 {
+				t.Fatalf("noise at (%d, %d) is %v, outside -1 to 1", x, y, v)
+			}
+			if v != noise.at(x, y, 0) {
+				t.Fatalf("noise at (%d, %d) changed between calls", x, y)
+			}
+			sum += v
+		}
+	}
+	if mean := sum / 1600; mean < -0.05 || mean > 0.05 {
+		t.Errorf("noise averages %v, want about 0", mean)
+	}
+	// Smooth noise passes through the lattice points.
+	if got, want := noise.smooth(12, 8, 4, 1), noise.at(3, 2, 1); got != want {
+		t.Errorf("smooth noise on a lattice point is %v, want %v", got, want)
+	}
+}
+
+func TestGrainIsSeeded(t *testing.T) {
+	img := flatImage(37, 23, color.Gray{128})
+	run := func(size float64, monochrome bool, seed uint64) *image.RGBA {
+		out, err := GrainT(30, size, monochrome, seed)(img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out.(*image.RGBA)
+	}
+
+	for _, size := range []float64{1, 3.5} {
+		for _, monochrome := range []bool{true, false} {
+			first := run(size, monochrome, 42)
+			if second := run(size, monochrome, 42); string(first.Pix) != string(second.Pix) {
+				t.Errorf("size %v, monochrome %v: the same seed gave different grain", size, monochrome)
+			}
+			if other := run(size, monochrome, 43); string(first.Pix) == string(other.Pix) {
+				t.Errorf("size %v, monochrome %v: different seeds gave the same grain", size, monochrome)
+			}
+			for i := 0; i < len(first.Pix); i += 4 {
+				for c := 0; c < 3; c++ {
+					if v := int(first.Pix[i+c]); v < 98 || v > 158 {
+						t.Fatalf("grain moved a channel to %d, more than the amount", v)
+					}
+				}
+				if monochrome && (first.Pix[i] != first.Pix[i+1] || first.Pix[i] != first.Pix[i+2]) {
+					t.Fatalf("monochrome grain made %v", first.Pix[i:i+4])
+				}
+			}
+		}
+	}
+}
+
+func TestGrainKeepsTransparency(t *testing.T) {
+	out, err := GrainT(255, 1, false, 1)(image.NewRGBA(image.Rect(0, 0, 8, 8)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range out.(*image.RGBA).Pix {
+		if v != 0 {
+			t.Fatal("grain showed up on transparent pixels")
+		}
+	}
+}
+
+func TestVignette(t *testing.T) {
+	img := flatImage(40, 20, color.White)
+	tests := []struct {
+		name       string
+		elliptical bool
+	}{
+		{"circular", false},
+		{"elliptical", true},
+	}
+	for _, tt := range tests {
+		out, err := VignetteT(1, 0.3, 0.4, color.NRGBA{0, 0, 0, 255}, tt.elliptical)(img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst := out.(*image.RGBA)
+		if got := dst.RGBAAt(20, 10).R; got != 255 {
+			t.Errorf("%s: the center became %d", tt.name, got)
+		}
+		if got := dst.RGBAAt(0, 0).R; got > 10 {
+			t.Errorf("%s: the corner is %d, want close to black", tt.name, got)
+		}
+		if edge, corner := dst.RGBAAt(0, 10).R, dst.RGBAAt(0, 0).R; edge < corner {
+			t.Errorf("%s: the edge is darker than the corner", tt.name)
+		}
+		if got := dst.RGBAAt(0, 0).A; got != 255 {
+			t.Errorf("%s: alpha became %d", tt.name, got)
+		}
+	}
+
+	// A circular vignette reaches the middle of the long sides sooner.
+	circular, _ := VignetteT(1, 0.3, 0.4, color.NRGBA{0, 0, 0, 255}, false)(img)
+	elliptical, _ := VignetteT(1, 0.3, 0.4, color.NRGBA{0, 0, 0, 255}, true)(img)
+	if c, e := circular.(*image.RGBA).RGBAAt(20, 0).R, elliptical.(*image.RGBA).RGBAAt(20, 0).R; c <= e {
+		t.Errorf("the top middle is %d circular and %d elliptical", c, e)
+	}
+}
+
+func TestLightLeak(t *testing.T) {
+	img := flatImage(30, 30, color.RGBA{0, 0, 100, 255})
+	out, err := LightLeakT(color.NRGBA{255, 0, 0, 255}, 0, 0, 0.5, 1)(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := out.(*image.RGBA)
+	// Screening red over blue brightens red and leaves blue alone.
+	if got := dst.RGBAAt(0, 0); got.R < 240 || got.B != 100 {
+		t.Errorf("the corner of the leak is %v", got)
+	}
+	if got := dst.RGBAAt(29, 29); got != img.RGBAAt(29, 29) {
+		t.Errorf("the far corner changed to %v", got)
+	}
+	if near, far := dst.RGBAAt(3, 3).R, dst.RGBAAt(8, 8).R; near <= far {
+		t.Errorf("the leak does not fade: %d near and %d farther", near, far)
+	}
+}
+
+func TestEffectArgs(t *testing.T) {
+	tests := []struct {
+		commands string
+		ok       bool
+	}{
+		{"vignette", true},
+		{"vignette,strength=1,radius=0,feather=0.2,color=#102030,elliptical=true", true},
+		{"vignette,strength=2", false},
+		{"vignette,feather=-1", false},
+		{"vignette,color=nope", false},
+		{"grain", true},
+		{"grain,amount=10,size=2,monochrome=false,seed=5", true},
+		{"grain,amount=300", false},
+		{"grain,size=0", false},
+		{"lightleak", true},
+		{"lightleak,color=#ffd700,x=1,y=0.5,radius=0.3,strength=0.2", true},
+		{"lightleak,radius=0", false},
+		{"lightleak,strength=-0.1", false},
+	}
+	for _, tt := range tests {
+		_, err := TokensToTfms(SplitCommands(tt.commands), 1)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.commands, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: font_test.go
+ * Description:
+ *   Tests for the built-in bitmap fonts.
+ */
+
+package main
+
+import "testing"
+
+func TestFontGlyphs(t *testing.T) {
+	for name, font := range FONTS {
+		for r := rune(' '); r <= '~'; r++ {
+			glyph, prs := font.glyphs[r]
+			if !prs {
+				t.Errorf("%s has no glyph for %q", name, r)
+				continue
+			}
+			if len(glyph) != font.height {
+				t.Errorf("%s: %q has %d rows, want %d", name, r, len(glyph), font.height)
+			}
+			for _, row := range glyph {
+				if row>>font.width != 0 {
+					t.Errorf("%s: %q has dots past its width", name, r)
+				}
+			}
+		}
+	}
+}
+
+func TestFontDot(t *testing.T) {
+	font := &FONT_5X7
+	tests := []struct {
+		r        rune
+		col, row int
+		want     bool
+	}{
+		// The top of an L is its leftmost dot, and its foot spans the width.
+		{'L', 0, 0, true},
+		{'L', 1, 0, false},
+		{'L', 4, 6, true},
+		{'!', 2, 0, true},
+		{'!', 2, 5, false},
+		{' ', 2, 3, false},
+	}
+	for _, tt := range tests {
+		if got := font.dot(tt.r, tt.col, tt.row); got != tt.want {
+			t.Errorf("dot(%q, %d, %d) = %v, want %v", tt.r, tt.col, tt.row, got, tt.want)
+		}
+	}
+
+	// Characters that the font doesn't have are drawn as its fallback.
+	for row := 0; row < font.height; row++ {
+		for col := 0; col < font.width; col++ {
+			if font.dot('€', col, row) != font.dot(font.fallback, col, row) {
+				t.Fatalf("€ is not drawn as %q", font.fallback)
+			}
+		}
+	}
+}
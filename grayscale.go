@@ -62,6 +62,16 @@ func GrayscaleParallel(img image.Image) (image.Image, error) {
         return grayImg, nil // No error in this case, but signature matches pipeline
 }
 
+// toGray returns the image itself if it is already grayscale, and
+// otherwise converts it with GrayscaleParallel.
+func toGray(img image.Image) *image.Gray {
+        if gray, ok := img.(*image.Gray); ok {
+                return gray
+        }
+        grayImg, _ := GrayscaleParallel(img)
+        return grayImg.(*image.Gray)
+}
+
 /*
 func main() {
         // Example pipeline usage:
@@ -0,0 +1,183 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: halftone.go
+ * Description:
+ *   Render images as CMYK or mono halftone screens of dots or lines.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// How far each CMYK screen is turned from the given angle, in degrees,
+// which keeps the screens from forming moire patterns.
+var CMYK_ANGLE_OFFSETS = [4]float64{-30, 30, -45, 0}
+
+/*
+ * One rotated halftone screen.
+ */
+type halftoneScreen struct {
+	cell float64
+	sin  float64
+	cos  float64
+}
+
+/*
+ * Make a screen with the given cell size, turned by angle degrees.
+ */
+func newHalftoneScreen(cell float64, angle float64) halftoneScreen {
+	rad := angle * math.Pi / 180
+	return halftoneScreen{cell, math.Sin(rad), math.Cos(rad)}
+}
+
+/*
+ * Find the center of the screen cell that a point lands in.
+ *
+ * Returns: The center in image space, and the point's offset from the
+ * center in screen space.
+ */
+func (s halftoneScreen) cellOf(x float64, y float64) (float64, float64, float64, float64) {
+	// Turn the point into screen space.
+	u := x*s.cos + y*s.sin
+	v := -x*s.sin + y*s.cos
+
+	cu := (math.Floor(u/s.cell) + 0.5) * s.cell
+	cv := (math.Floor(v/s.cell) + 0.5) * s.cell
+
+	// Turn the center back into image space.
+	cx := cu*s.cos - cv*s.sin
+	cy := cu*s.sin + cv*s.cos
+	return cx, cy, u - cu, v - cv
+}
+
+/*
+ * Get how much of a point is covered by ink, from 0 to 1, for a cell with
+ * the given ink level. Edges are softened over about one pixel.
+ */
+func (s halftoneScreen) coverage(du float64, dv float64, ink float64, shape string) float64 {
+	if shape == "line" {
+		half := ink * s.cell / 2
+		return smoothstep(-0.5, 0.5, half-math.Abs(dv))
+	}
+	// Dots grow with the square root of the ink level, and full ink reaches
+	// the corners of the cell so that solid areas have no gaps.
+	radius := s.cell / math.Sqrt2 * math.Sqrt(ink)
+	return smoothstep(-0.5, 0.5, radius-math.Hypot(du, dv))
+}
+
+/*
+ * Convert a color into CMYK ink levels from 0 to 1.
+ */
+func inkLevels(c color.Color) [4]float64 {
+	r, g, b, _ := c.RGBA()
+	cc, mm, yy, kk := color.RGBToCMYK(uint8(r>>8), uint8(g>>8), uint8(b>>8))
+	return [4]float64{float64(cc) / 255, float64(mm) / 255, float64(yy) / 255, float64(kk) / 255}
+}
+
+/*
+ * Get a function that will render any image as a halftone. In cmyk mode
+ * there is a screen per ink, each turned a little from angle; in mono mode
+ * there is a single black screen. Every cell takes its ink level from the
+ * color at its center.
+ */
+func HalftoneT(cell float64, angle float64, shape string, cmyk bool) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		bounds := img.Bounds()
+		dst := image.NewRGBA(bounds)
+
+		numScreens := 1
+		if cmyk {
+			numScreens = 4
+		}
+		screens := make([]halftoneScreen, numScreens)
+		for i := range screens {
+			if cmyk {
+				screens[i] = newHalftoneScreen(cell, angle+CMYK_ANGLE_OFFSETS[i])
+			} else {
+				screens[i] = newHalftoneScreen(cell, angle)
+			}
+		}
+
+		sample := func(x float64, y float64) color.Color {
+			return img.At(
+				clamp(int(math.Floor(x)), bounds.Min.X, bounds.Max.X-1),
+				clamp(int(math.Floor(y)), bounds.Min.Y, bounds.Max.Y-1),
+			)
+		}
+
+		pool := GetGlobalWorkers()
+		pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+			for y := lo; y < hi; y++ {
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					px, py := float64(x)+0.5, float64(y)+0.5
+
+					var covered [4]float64
+					for i, screen := range screens {
+						cx, cy, du, dv := screen.cellOf(px, py)
+						var ink float64
+						if cmyk {
+							ink = inkLevels(sample(cx, cy))[i]
+						} else {
+							ink = 1 - float64(color.GrayModel.Convert(sample(cx, cy)).(color.Gray).Y)/255
+						}
+						covered[i] = screen.coverage(du, dv, ink, shape)
+					}
+
+					_, _, _, a := img.At(x, y).RGBA()
+					alpha := float64(a>>8) / 255
+					var out [3]float64
+					if cmyk {
+						k := 1 - covered[3]
+						out = [3]float64{(1 - covered[0]) * k, (1 - covered[1]) * k, (1 - covered[2]) * k}
+					} else {
+						out = [3]float64{1 - covered[0], 1 - covered[0], 1 - covered[0]}
+					}
+
+					i := dst.PixOffset(x, y)
+					dst.Pix[i] = uint8(math.Round(out[0] * alpha * 255))
+					dst.Pix[i+1] = uint8(math.Round(out[1] * alpha * 255))
+					dst.Pix[i+2] = uint8(math.Round(out[2] * alpha * 255))
+					dst.Pix[i+3] = uint8(a >> 8)
+				}
+			}
+		})
+		return dst, nil
+	}
+}
+
+/*
+ * Build a halftone transformation from its command line arguments.
+ */
+func halftoneFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("cell", "angle", "shape", "mode"); err != nil {
+		return nil, err
+	}
+
+	cell, err := args.getFloat("cell", 8)
+	if err != nil {
+		return nil, err
+	}
+	angle, err := args.getFloat("angle", 45)
+	if err != nil {
+		return nil, err
+	}
+	if cell < 2 {
+		return nil, errors.New("cell must be at least 2")
+	}
+
+	shape := args.getString("shape", "dot")
+	if shape != "dot" && shape != "line" {
+		return nil, fmt.Errorf("%s is not a valid shape", shape)
+	}
+	mode := args.getString("mode", "cmyk")
+	if mode != "cmyk" && mode != "mono" {
+		return nil, fmt.Errorf("%s is not a valid mode", mode)
+	}
+	return HalftoneT(cell, angle, shape, mode == "cmyk"), nil
+}
@@ -0,0 +1,142 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: halftone_test.go
+ * Description:
+ *   Tests for halftone screens.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestHalftoneCellOf(t *testing.T) {
+	for _, angle := range []float64{0, 15, 45, 75, -30} {
+		screen := newHalftoneScreen(6, angle)
+		for y := -7.0; y < 20; y += 1.3 {
+			for x := -3.0; x < 20; x += 0.7 {
+				cx, cy, du, dv := screen.cellOf(x, y)
+				if math.Abs(du) > 3 || math.Abs(dv) > 3 {
+					t.Fatalf("angle %v: (%v, %v) is %v, %v from its cell center", angle, x, y, du, dv)
+				}
+				// The offset is the point minus the center, in screen space.
+				ox, oy := x-cx, y-cy
+				u := ox*screen.cos + oy*screen.sin
+				v := -ox*screen.sin + oy*screen.cos
+				if math.Abs(u-du) > 1e-9 || math.Abs(v-dv) > 1e-9 {
+					t.Fatalf("angle %v: offset of (%v, %v) is %v, %v, want %v, %v", angle, x, y, du, dv, u, v)
+				}
+			}
+		}
+	}
+}
+
+func TestInkLevels(t *testing.T) {
+	tests := []struct {
+		clr  color.Color
+		want [4]float64
+	}{
+		{color.White, [4]float64{0, 0, 0, 0}},
+		{color.Black, [4]float64{0, 0, 0, 1}},
+		{color.RGBA{255, 0, 0, 255}, [4]float64{0, 1, 1, 0}},
+		{color.RGBA{0, 255, 255, 255}, [4]float64{1, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		if got := inkLevels(tt.clr); got != tt.want {
+			t.Errorf("inkLevels(%v) = %v, want %v", tt.clr, got, tt.want)
+		}
+	}
+}
+
+func TestHalftoneSolidColors(t *testing.T) {
+	tests := []struct {
+		name  string
+		clr   color.Color
+		shape string
+		want  uint8
+	}{
+		{"white dots", color.White, "dot", 255},
+		{"white lines", color.White, "line", 255},
+		{"black dots", color.Black, "dot", 0},
+		{"black lines", color.Black, "line", 0},
+	}
+	for _, tt := range tests {
+		out, err := HalftoneT(8, 0, tt.shape, false)(flatImage(24, 24, tt.clr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, v := range out.(*image.RGBA).Pix {
+			want := tt.want
+			if i%4 == 3 {
+				want = 255
+			}
+			if v != want {
+				t.Fatalf("%s: byte %d is %d, want %d", tt.name, i, v, want)
+			}
+		}
+	}
+}
+
+func TestHalftoneDarkensWithInk(t *testing.T) {
+	mean := func(shape string, level uint8) float64 {
+		out, err := HalftoneT(8, 0, shape, false)(flatImage(64, 64, color.Gray{level}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sum float64
+		pix := out.(*image.RGBA).Pix
+		for i := 0; i < len(pix); i += 4 {
+			sum += float64(pix[i])
+		}
+		return sum / float64(len(pix)/4)
+	}
+	for _, shape := range []string{"dot", "line"} {
+		light, mid, dark := mean(shape, 192), mean(shape, 128), mean(shape, 64)
+		if !(light > mid && mid > dark) || dark == 0 || light == 255 {
+			t.Errorf("%s: light, mid and dark grays average %v, %v and %v", shape, light, mid, dark)
+		}
+	}
+	// Lines cover the cell in proportion to the ink.
+	if got := mean("line", 128); got < 120 || got > 136 {
+		t.Errorf("half ink in lines averages %v, want about 128", got)
+	}
+}
+
+func TestHalftoneCMYKKeepsPaperAndAlpha(t *testing.T) {
+	img := flatImage(16, 16, color.White)
+	img.SetRGBA(3, 3, color.RGBA{})
+	out, err := HalftoneT(4, 45, "dot", true)(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := out.(*image.RGBA)
+	if got := dst.RGBAAt(10, 10); got != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("white paper became %v", got)
+	}
+	if got := dst.RGBAAt(3, 3); got != (color.RGBA{}) {
+		t.Errorf("a transparent pixel became %v", got)
+	}
+}
+
+func TestHalftoneArgs(t *testing.T) {
+	tests := []struct {
+		commands string
+		ok       bool
+	}{
+		{"halftone", true},
+		{"halftone,cell=4,angle=0,shape=line,mode=mono", true},
+		{"halftone,cell=1", false},
+		{"halftone,shape=square", false},
+		{"halftone,mode=rgb", false},
+	}
+	for _, tt := range tests {
+		_, err := TokensToTfms(SplitCommands(tt.commands), 1)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.commands, err, tt.ok)
+		}
+	}
+}
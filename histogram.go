@@ -0,0 +1,41 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: histogram.go
+ * Description:
+ *   Parallel histograms of grayscale images.
+ */
+
+package main
+
+import (
+	"image"
+	"sync"
+)
+
+/*
+ * Count how many pixels of a grayscale image have each brightness. Every
+ * strip of rows is counted on its own, and the counts are merged at the end.
+ */
+func grayHistogram(gray *image.Gray) [256]int {
+	bounds := gray.Bounds()
+	var hist [256]int
+	var mu sync.Mutex
+
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+		var local [256]int
+		for y := lo; y < hi; y++ {
+			row := gray.Pix[gray.PixOffset(bounds.Min.X, y):gray.PixOffset(bounds.Max.X, y)]
+			for _, v := range row {
+				local[v]++
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for i, n := range local {
+			hist[i] += n
+		}
+	})
+	return hist
+}
@@ -9,12 +9,12 @@ import (
         "fmt"
         "image"
         "image/draw"
+        "image/gif"
         "image/jpeg"
         "image/png"
         "log"
         "os"
         "path/filepath"
-	"math/rand"
 	"runtime"
         "strings"
 	"time"
@@ -33,15 +33,42 @@ func GetGlobalWorkers() WorkerPool {
 }
 
 func main() {
-        // Seed rand for transformations that may use it.
-	rand.Seed(time.Now().UnixNano())
+        // Subcommands get their own flags.
+        if len(os.Args) > 1 && os.Args[1] == "analyze" {
+                if err := runAnalyze(os.Args[2:]); err != nil {
+                        log.Fatalf("Analyze failed: %v", err)
+                }
+                return
+        }
 
         // Parse flags
         inputPath := flag.String("i", "", "input image path")
         outputPath := flag.String("o", "output.jpg", "output image path")
         commands := flag.String("c", "", "transformation commands (e.g. blur, grayscale, upsidedown, cats)")
+        asciiCols := flag.Int("cols", 100, "width in characters of .txt or .ans ASCII art output")
+        assetsDir := flag.String("assets", "", "directory of assets to use in place of the built-in ones")
+        seed := flag.Int64("seed", 0, "seed for random transformations such as cats (default: based on the time)")
         flag.Parse()
 
+        // Without -seed, pick one from the time. It is printed so that the
+        // run can be replayed.
+        seedGiven := false
+        flag.Visit(func(f *flag.Flag) {
+                if f.Name == "seed" {
+                        seedGiven = true
+                }
+        })
+        if !seedGiven {
+                *seed = time.Now().UnixNano()
+        }
+        fmt.Printf("Using seed %d\n", *seed)
+
+        if *assetsDir != "" {
+                if err := useAssetsDir(*assetsDir); err != nil {
+                        log.Fatalf("Assets failed: %v", err)
+                }
+        }
+
         // Validate input
         if *inputPath == "" {
                 log.Fatal("Input path is required")
@@ -49,7 +76,7 @@ func main() {
         }
 
         // Parse commands into parallel transformations
-        tfms, err := TokensToTfms(strings.Split(*commands, ","))
+        tfms, err := TokensToTfms(SplitCommands(*commands), *seed)
         if err != nil {
                 log.Fatalf("Command parsing failed: %v", err)
 		os.Exit(1)
@@ -63,11 +90,7 @@ func main() {
         }
 
         // Convert to RGBA if needed (for parallel transforms)
-        if _, ok := img.(*image.RGBA); !ok {
-                rgba := image.NewRGBA(img.Bounds())
-                draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
-                img = rgba
-        }
+        img = toRGBA(img)
 
 	// Start a global worker pool
 	numCpu := runtime.NumCPU()
@@ -81,17 +104,23 @@ func main() {
 		os.Exit(1)
         }
 
-	// Wait for all tasks to complete, and then stop workers.
-	pool.WaitAndStop()
-
         fmt.Println("Saving output image")
 
-        // Save output
-        if err := saveImage(result, *outputPath); err != nil {
+        // Save output. ASCII art is built on the worker pool, so the pool
+        // is only stopped once the output is saved.
+        if isAsciiPath(*outputPath) {
+                err = saveAscii(result, *outputPath, *asciiCols)
+        } else {
+                err = saveImage(result, *outputPath)
+        }
+        if err != nil {
                 log.Fatalf("Save failed: %v", err)
 		os.Exit(1)
         }
 
+	// Wait for all tasks to complete, and then stop workers.
+	pool.WaitAndStop()
+
         fmt.Printf("Success! Saved to %s\n", *outputPath)
 }
 
@@ -106,7 +135,20 @@ func decodeImage(path string) (image.Image, error) {
 	return img, err
 }
 
-// saveImage encodes based on file extension
+// toRGBA returns the image itself if it is already RGBA, and otherwise
+// copies it into a new RGBA image with the same bounds
+func toRGBA(img image.Image) *image.RGBA {
+        if rgba, ok := img.(*image.RGBA); ok {
+                return rgba
+        }
+        rgba := image.NewRGBA(img.Bounds())
+        draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+        return rgba
+}
+
+// saveImage encodes based on file extension. Paletted images, such as the
+// output of quantize, are written as indexed PNGs and use their own palette
+// as the GIF color table.
 func saveImage(img image.Image, path string) error {
         file, err := os.Create(path)
         if err != nil {
@@ -116,7 +158,10 @@ func saveImage(img image.Image, path string) error {
 
         switch strings.ToLower(filepath.Ext(path)) {
         case ".png":
-                return png.Encode(file, img)
+                encoder := png.Encoder{CompressionLevel: png.BestCompression}
+                return encoder.Encode(file, img)
+        case ".gif":
+                return gif.Encode(file, img, nil)
         default: // Default to JPEG
                 return jpeg.Encode(file, img, &jpeg.Options{Quality: 90})
         }
@@ -0,0 +1,238 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: morphology.go
+ * Description:
+ *   Morphological operations with configurable structuring elements.
+ *   Grayscale images stay grayscale, and any other image is processed one
+ *   RGBA channel at a time.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+/*
+ * A grayscale or RGBA image viewed as raw channel bytes.
+ */
+type pixelBuffer struct {
+	img      image.Image
+	pix      []uint8
+	stride   int
+	channels int
+	bounds   image.Rectangle
+}
+
+/*
+ * Wrap an image in a pixel buffer. Grayscale images are used as they are,
+ * and everything else is converted to RGBA.
+ */
+func newPixelBuffer(img image.Image) pixelBuffer {
+	if gray, ok := img.(*image.Gray); ok {
+		return pixelBuffer{gray, gray.Pix, gray.Stride, 1, gray.Bounds()}
+	}
+	rgba := toRGBA(img)
+	return pixelBuffer{rgba, rgba.Pix, rgba.Stride, 4, rgba.Bounds()}
+}
+
+/*
+ * Make an empty pixel buffer of the same kind and size.
+ */
+func (b pixelBuffer) blank() pixelBuffer {
+	if b.channels == 1 {
+		return newPixelBuffer(image.NewGray(b.bounds))
+	}
+	return newPixelBuffer(image.NewRGBA(b.bounds))
+}
+
+/*
+ * Get the index of the first channel of a pixel.
+ */
+func (b pixelBuffer) offset(x int, y int) int {
+	return (y-b.bounds.Min.Y)*b.stride + (x-b.bounds.Min.X)*b.channels
+}
+
+/*
+ * Build the offsets covered by a structuring element of the given shape
+ * and size, centered on the origin.
+ */
+func structuringElement(shape string, size int) ([]image.Point, error) {
+	if size < 1 {
+		return nil, errors.New("size must be at least 1")
+	}
+	radius := size / 2
+	// Even sizes reach one pixel further on the low side.
+	lo, hi := -radius, size-radius-1
+
+	points := make([]image.Point, 0, size*size)
+	for dy := lo; dy <= hi; dy++ {
+		for dx := lo; dx <= hi; dx++ {
+			switch shape {
+			case "rect":
+			case "cross":
+				if dx != 0 && dy != 0 {
+					continue
+				}
+			case "ellipse":
+				// Measure from the true center, which is between pixels for
+				// even sizes.
+				center := float64(lo+hi) / 2
+				r := float64(size) / 2
+				fx := (float64(dx) - center) / r
+				fy := (float64(dy) - center) / r
+				if fx*fx+fy*fy > 1 {
+					continue
+				}
+			default:
+				return nil, fmt.Errorf("%s is not a valid shape", shape)
+			}
+			points = append(points, image.Point{dx, dy})
+		}
+	}
+	return points, nil
+}
+
+/*
+ * Erode (take the minimum) or dilate (take the maximum) every channel over
+ * the structuring element. Rows are split between workers, and pixels past
+ * the border are clamped like in blur.
+ */
+func erodeDilate(src pixelBuffer, element []image.Point, erode bool) pixelBuffer {
+	dst := src.blank()
+	bounds := src.bounds
+
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				out := dst.offset(x, y)
+				for c := 0; c < src.channels; c++ {
+					best := uint8(0)
+					if erode {
+						best = 255
+					}
+					for _, p := range element {
+						px := clamp(x+p.X, bounds.Min.X, bounds.Max.X-1)
+						py := clamp(y+p.Y, bounds.Min.Y, bounds.Max.Y-1)
+						v := src.pix[src.offset(px, py)+c]
+						if erode {
+							best = min(best, v)
+						} else {
+							best = max(best, v)
+						}
+					}
+					dst.pix[out+c] = best
+				}
+			}
+		}
+	})
+	return dst
+}
+
+/*
+ * Subtract one buffer from another channel by channel, stopping at 0.
+ */
+func subtractBuffers(a pixelBuffer, b pixelBuffer) pixelBuffer {
+	dst := a.blank()
+	bounds := a.bounds
+
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				i, j, k := a.offset(x, y), b.offset(x, y), dst.offset(x, y)
+				for c := 0; c < a.channels; c++ {
+					if a.pix[i+c] > b.pix[j+c] {
+						dst.pix[k+c] = a.pix[i+c] - b.pix[j+c]
+					}
+				}
+			}
+		}
+	})
+	return dst
+}
+
+/*
+ * Get a function that will run a morphological operation on any image:
+ *   erode, dilate: shrink or grow bright areas
+ *   open: erode then dilate, which removes small bright specks
+ *   close: dilate then erode, which fills small dark holes
+ *   tophat: the image minus its opening, which keeps only small bright details
+ *   morphgradient: dilation minus erosion, which outlines shapes
+ *
+ * For RGBA images the alpha channel is processed too, since masks often
+ * live in it.
+ */
+func MorphT(op string, element []image.Point) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		src := newPixelBuffer(img)
+
+		var res pixelBuffer
+		switch op {
+		case "erode":
+			res = erodeDilate(src, element, true)
+		case "dilate":
+			res = erodeDilate(src, element, false)
+		case "open":
+			res = erodeDilate(erodeDilate(src, element, true), element, false)
+		case "close":
+			res = erodeDilate(erodeDilate(src, element, false), element, true)
+		case "tophat":
+			opened := erodeDilate(erodeDilate(src, element, true), element, false)
+			res = subtractBuffers(src, opened)
+		case "morphgradient":
+			res = subtractBuffers(
+				erodeDilate(src, element, false),
+				erodeDilate(src, element, true),
+			)
+		default:
+			return nil, fmt.Errorf("%s is not a valid morphological operation", op)
+		}
+
+		if op == "tophat" || op == "morphgradient" {
+			// A difference of alphas would hide the result, so keep the
+			// original alpha.
+			keepAlpha(res, src)
+		}
+		return res.img, nil
+	}
+}
+
+/*
+ * Copy the alpha channel of src into dst when both are RGBA.
+ */
+func keepAlpha(dst pixelBuffer, src pixelBuffer) {
+	if dst.channels != 4 {
+		return
+	}
+	for y := dst.bounds.Min.Y; y < dst.bounds.Max.Y; y++ {
+		for x := dst.bounds.Min.X; x < dst.bounds.Max.X; x++ {
+			dst.pix[dst.offset(x, y)+3] = src.pix[src.offset(x, y)+3]
+		}
+	}
+}
+
+/*
+ * Get a function that builds a morphological transformation from its
+ * command line arguments.
+ */
+func morphFromArgs(op string) func(tfmArgs) (func(image.Image) (image.Image, error), error) {
+	return func(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+		if err := args.allow("shape", "size"); err != nil {
+			return nil, err
+		}
+
+		size, err := args.getInt("size", 3)
+		if err != nil {
+			return nil, err
+		}
+		element, err := structuringElement(args.getString("shape", "rect"), size)
+		if err != nil {
+			return nil, err
+		}
+		return MorphT(op, element), nil
+	}
+}
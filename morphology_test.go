@@ -0,0 +1,184 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: morphology_test.go
+ * Description:
+ *   Tests for structuring elements and morphological operations.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+func TestStructuringElement(t *testing.T) {
+	tests := []struct {
+		shape string
+		size  int
+		count int
+		lo    int
+		hi    int
+	}{
+		{"rect", 1, 1, 0, 0},
+		{"rect", 3, 9, -1, 1},
+		{"rect", 4, 16, -2, 1},
+		{"cross", 3, 5, -1, 1},
+		{"cross", 5, 9, -2, 2},
+		{"ellipse", 1, 1, 0, 0},
+		{"ellipse", 3, 9, -1, 1},
+		{"ellipse", 5, 21, -2, 2},
+	}
+	for _, tt := range tests {
+		points, err := structuringElement(tt.shape, tt.size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(points) != tt.count {
+			t.Errorf("%s of size %d has %d points, want %d", tt.shape, tt.size, len(points), tt.count)
+		}
+		for _, p := range points {
+			if p.X < tt.lo || p.X > tt.hi || p.Y < tt.lo || p.Y > tt.hi {
+				t.Errorf("%s of size %d reaches %v, outside %d to %d", tt.shape, tt.size, p, tt.lo, tt.hi)
+			}
+		}
+	}
+
+	if _, err := structuringElement("rect", 0); err == nil {
+		t.Error("a size of 0 should be rejected")
+	}
+	if _, err := structuringElement("diamond", 3); err == nil {
+		t.Error("diamond should not be a valid shape")
+	}
+}
+
+func TestErodeDilateMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	gray := image.NewGray(image.Rect(2, 1, 15, 12))
+	rng.Read(gray.Pix)
+	bounds := gray.Bounds()
+	element, err := structuringElement("cross", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, erode := range []bool{true, false} {
+		dst := erodeDilate(newPixelBuffer(gray), element, erode)
+		if dst.channels != 1 {
+			t.Fatalf("a grayscale image became %d channels", dst.channels)
+		}
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				want := gray.GrayAt(x, y).Y
+				for _, p := range element {
+					px := clamp(x+p.X, bounds.Min.X, bounds.Max.X-1)
+					py := clamp(y+p.Y, bounds.Min.Y, bounds.Max.Y-1)
+					v := gray.GrayAt(px, py).Y
+					if erode {
+						want = min(want, v)
+					} else {
+						want = max(want, v)
+					}
+				}
+				if got := dst.pix[dst.offset(x, y)]; got != want {
+					t.Errorf("erode %v: pixel at (%d, %d) = %d, want %d", erode, x, y, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestMorphOperations(t *testing.T) {
+	// A 6x6 white square and a single white speck in a black image.
+	img := squareImage(image.Pt(20, 20), image.Rect(4, 4, 10, 10))
+	img.Set(15, 15, color.White)
+	element, err := structuringElement("rect", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		op  string
+		on  []image.Point
+		off []image.Point
+	}{
+		{"erode", []image.Point{{5, 5}, {8, 8}}, []image.Point{{4, 4}, {15, 15}}},
+		{"dilate", []image.Point{{3, 3}, {10, 10}, {16, 16}}, []image.Point{{2, 2}, {17, 17}}},
+		{"open", []image.Point{{4, 4}, {9, 9}}, []image.Point{{15, 15}, {3, 3}}},
+		{"close", []image.Point{{4, 4}, {15, 15}}, []image.Point{{3, 3}, {16, 16}}},
+		{"tophat", []image.Point{{15, 15}}, []image.Point{{4, 4}, {6, 6}, {0, 0}}},
+		{"morphgradient", []image.Point{{3, 3}, {4, 4}, {10, 10}}, []image.Point{{6, 6}, {0, 0}}},
+	}
+	for _, tt := range tests {
+		out, err := MorphT(tt.op, element)(img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rgba := out.(*image.RGBA)
+		for _, p := range tt.on {
+			if got := rgba.RGBAAt(p.X, p.Y).R; got != 255 {
+				t.Errorf("%s: pixel at %v = %d, want 255", tt.op, p, got)
+			}
+		}
+		for _, p := range tt.off {
+			if got := rgba.RGBAAt(p.X, p.Y).R; got != 0 {
+				t.Errorf("%s: pixel at %v = %d, want 0", tt.op, p, got)
+			}
+		}
+		// Every pixel is opaque in the source, and stays that way.
+		for i := 3; i < len(rgba.Pix); i += 4 {
+			if rgba.Pix[i] != 255 {
+				t.Fatalf("%s: alpha became %d", tt.op, rgba.Pix[i])
+			}
+		}
+	}
+
+	if _, err := MorphT("skeleton", element)(img); err == nil {
+		t.Error("skeleton should not be a valid operation")
+	}
+}
+
+func TestOpenAndCloseAreIdempotent(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	gray := image.NewGray(image.Rect(0, 0, 17, 13))
+	rng.Read(gray.Pix)
+	element, err := structuringElement("ellipse", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range []string{"open", "close"} {
+		once, err := MorphT(op, element)(gray)
+		if err != nil {
+			t.Fatal(err)
+		}
+		twice, err := MorphT(op, element)(once)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(once.(*image.Gray).Pix) != string(twice.(*image.Gray).Pix) {
+			t.Errorf("running %s twice changed the image", op)
+		}
+	}
+}
+
+func TestMorphArgs(t *testing.T) {
+	tests := []struct {
+		commands string
+		ok       bool
+	}{
+		{"erode", true},
+		{"dilate,shape=cross,size=5", true},
+		{"open,shape=ellipse,size=2", true},
+		{"close,size=0", false},
+		{"tophat,shape=star", false},
+		{"morphgradient,radius=3", false},
+	}
+	for _, tt := range tests {
+		_, err := TokensToTfms(SplitCommands(tt.commands), 1)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.commands, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: overlay.go
+ * Description:
+ *   Blend a layer over an image with the standard separable blend modes.
+ *   The layer can be an image file, or a snapshot of an earlier step of
+ *   the pipeline.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"sync"
+)
+
+/*
+ * A blend mode, which mixes a backdrop color b with a source color s. Both
+ * are channels from 0 to 1 without alpha.
+ */
+type blendMode func(b float64, s float64) float64
+
+/*
+ * Apply the soft light blend mode, which darkens or lightens the backdrop
+ * depending on the source, like a diffused spotlight.
+ */
+func softLight(b float64, s float64) float64 {
+	if s <= 0.5 {
+		return b - (1-2*s)*b*(1-b)
+	}
+	d := math.Sqrt(b)
+	if b <= 0.25 {
+		d = ((16*b-12)*b + 4) * b
+	}
+	return b + (2*s-1)*(d-b)
+}
+
+// The blend modes that a layer can be blended with.
+var BLEND_MODES = map[string]blendMode{
+	"normal":   func(b float64, s float64) float64 { return s },
+	"multiply": func(b float64, s float64) float64 { return b * s },
+	"screen":   func(b float64, s float64) float64 { return b + s - b*s },
+	"overlay": func(b float64, s float64) float64 {
+		if b <= 0.5 {
+			return 2 * b * s
+		}
+		return 1 - 2*(1-b)*(1-s)
+	},
+	"softlight":  softLight,
+	"darken":     math.Min,
+	"lighten":    math.Max,
+	"difference": func(b float64, s float64) float64 { return math.Abs(b - s) },
+	"add":        func(b float64, s float64) float64 { return math.Min(1, b+s) },
+}
+
+/*
+ * Named snapshots of the image as it goes through the pipeline, so that a
+ * later step can blend in an earlier result.
+ */
+type layerStore struct {
+	sync.Mutex
+	layers map[string]image.Image
+	names  map[string]bool
+}
+
+/*
+ * Make an empty layer store.
+ */
+func newLayerStore() *layerStore {
+	return &layerStore{layers: make(map[string]image.Image), names: make(map[string]bool)}
+}
+
+/*
+ * Get a function that saves the image under a name and passes it on
+ * unchanged.
+ */
+func SnapshotT(layers *layerStore, name string) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		layers.Lock()
+		defer layers.Unlock()
+		layers.layers[name] = img
+		return img, nil
+	}
+}
+
+/*
+ * Blend a layer over an image, with the top left of the layer at offset
+ * from the top left of the image. Outside of the layer the image is
+ * unchanged. The blended color only shows where both are opaque; elsewhere
+ * the layer is composited over the image as it is.
+ */
+func blendLayer(img image.Image, layer image.Image, mode blendMode, opacity float64, offset image.Point) *image.RGBA {
+	newImg := copyRGBA(img)
+	bounds := newImg.Bounds()
+	top := toRGBA(layer)
+	area := top.Bounds().Sub(top.Bounds().Min).Add(bounds.Min).Add(offset).Intersect(bounds)
+	shift := top.Bounds().Min.Sub(bounds.Min).Sub(offset)
+
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(area.Min.Y, area.Max.Y, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := area.Min.X; x < area.Max.X; x++ {
+				i := newImg.PixOffset(x, y)
+				j := top.PixOffset(x+shift.X, y+shift.Y)
+				ab := float64(newImg.Pix[i+3]) / 255
+				as := float64(top.Pix[j+3]) / 255 * opacity
+				if as == 0 {
+					continue
+				}
+
+				ao := as + ab*(1-as)
+				for c := 0; c < 3; c++ {
+					// Unpremultiply to blend, then composite premultiplied.
+					var cb, cs float64
+					if ab > 0 {
+						cb = float64(newImg.Pix[i+c]) / 255 / ab
+					}
+					cs = float64(top.Pix[j+c]) / 255 / (float64(top.Pix[j+3]) / 255)
+					mixed := (1-ab)*cs + ab*mode(cb, cs)
+					co := as*mixed + ab*cb*(1-as)
+					newImg.Pix[i+c] = uint8(math.Round(clampUnit(co) * 255))
+				}
+				newImg.Pix[i+3] = uint8(math.Round(clampUnit(ao) * 255))
+			}
+		}
+	})
+	return newImg
+}
+
+/*
+ * Clamp a value to the range from 0 to 1.
+ */
+func clampUnit(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+/*
+ * Get a function that will blend a layer over any image. The layer is
+ * either an image, or if layer is nil, the snapshot with the given name.
+ */
+func OverlayT(layer image.Image, layers *layerStore, name string, mode blendMode, opacity float64, offset image.Point) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		top := layer
+		if top == nil {
+			layers.Lock()
+			top = layers.layers[name]
+			layers.Unlock()
+			if top == nil {
+				return nil, fmt.Errorf("there is no snapshot called %s yet", name)
+			}
+		}
+		return blendLayer(img, top, mode, opacity, offset), nil
+	}
+}
+
+/*
+ * Build a snapshot transformation from its command line arguments.
+ */
+func snapshotFromArgs(args tfmArgs, layers *layerStore) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("name"); err != nil {
+		return nil, err
+	}
+	name := args.getString("name", "")
+	if name == "" {
+		return nil, errors.New("a name must be given")
+	}
+	layers.names[name] = true
+	return SnapshotT(layers, name), nil
+}
+
+/*
+ * Build an overlay transformation from its command line arguments. The
+ * layer is a file, or a snapshot taken earlier in the pipeline.
+ */
+func overlayFromArgs(args tfmArgs, layers *layerStore) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("file", "layer", "mode", "opacity", "x", "y"); err != nil {
+		return nil, err
+	}
+	if args.has("file") == args.has("layer") {
+		return nil, errors.New("either a file or a layer must be given")
+	}
+
+	modeName := args.getString("mode", "normal")
+	mode, prs := BLEND_MODES[modeName]
+	if !prs {
+		return nil, fmt.Errorf("%s is not a valid mode", modeName)
+	}
+	opacity, err := args.getFloat("opacity", 1)
+	if err != nil {
+		return nil, err
+	}
+	x, err := args.getInt("x", 0)
+	if err != nil {
+		return nil, err
+	}
+	y, err := args.getInt("y", 0)
+	if err != nil {
+		return nil, err
+	}
+	if opacity < 0 || opacity > 1 {
+		return nil, errors.New("opacity must be between 0 and 1")
+	}
+
+	name := args.getString("layer", "")
+	var layer image.Image
+	if args.has("file") {
+		layer, err = decodeImage(args.getString("file", ""))
+		if err != nil {
+			return nil, err
+		}
+	} else if !layers.names[name] {
+		return nil, fmt.Errorf("there is no snapshot called %s before this", name)
+	}
+	return OverlayT(layer, layers, name, mode, opacity, image.Pt(x, y)), nil
+}
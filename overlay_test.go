@@ -0,0 +1,166 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: overlay_test.go
+ * Description:
+ *   Tests for blend modes and blending layers over images.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+)
+
+func TestBlendModes(t *testing.T) {
+	tests := []struct {
+		mode string
+		b, s float64
+		want float64
+	}{
+		{"normal", 0.2, 0.7, 0.7},
+		{"multiply", 0.5, 0.5, 0.25},
+		{"multiply", 0.8, 1, 0.8},
+		{"screen", 0.5, 0.5, 0.75},
+		{"screen", 0.8, 0, 0.8},
+		{"overlay", 0.25, 0.5, 0.25},
+		{"overlay", 0.75, 0.5, 0.75},
+		{"overlay", 0.25, 1, 0.5},
+		{"overlay", 0.75, 0, 0.5},
+		{"softlight", 0.3, 0.5, 0.3},
+		{"softlight", 0.25, 1, 0.5},
+		{"softlight", 0.64, 1, 0.8},
+		{"softlight", 0.5, 0, 0.25},
+		{"darken", 0.2, 0.7, 0.2},
+		{"lighten", 0.2, 0.7, 0.7},
+		{"difference", 0.2, 0.7, 0.5},
+		{"difference", 0.7, 0.2, 0.5},
+		{"add", 0.2, 0.3, 0.5},
+		{"add", 0.6, 0.7, 1},
+	}
+	for _, tt := range tests {
+		if got := BLEND_MODES[tt.mode](tt.b, tt.s); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s(%v, %v) = %v, want %v", tt.mode, tt.b, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestBlendModesStayInRange(t *testing.T) {
+	for name, mode := range BLEND_MODES {
+		for b := 0.0; b <= 1; b += 0.05 {
+			for s := 0.0; s <= 1; s += 0.05 {
+				if v := mode(b, s); v < -1e-9 || v > 1+1e-9 {
+					t.Fatalf("%s(%v, %v) = %v, outside 0 to 1", name, b, s, v)
+				}
+			}
+		}
+	}
+}
+
+func TestBlendLayer(t *testing.T) {
+	gray := color.RGBA{128, 128, 128, 255}
+	tests := []struct {
+		name    string
+		img     color.Color
+		layer   color.Color
+		mode    string
+		opacity float64
+		want    color.RGBA
+	}{
+		{"multiply", gray, gray, "multiply", 1, color.RGBA{64, 64, 64, 255}},
+		{"screen", gray, gray, "screen", 1, color.RGBA{192, 192, 192, 255}},
+		{"half opacity", color.Black, color.White, "normal", 0.5, color.RGBA{128, 128, 128, 255}},
+		// Over nothing the layer is drawn as it is, whatever the mode.
+		{"transparent image", color.Transparent, color.RGBA{255, 0, 0, 255}, "multiply", 1, color.RGBA{255, 0, 0, 255}},
+		{"transparent layer", gray, color.Transparent, "difference", 1, gray},
+		{"translucent layer", color.Black, color.RGBA{128, 128, 128, 128}, "normal", 1, color.RGBA{128, 128, 128, 255}},
+	}
+	for _, tt := range tests {
+		out := blendLayer(flatImage(2, 2, tt.img), flatImage(2, 2, tt.layer), BLEND_MODES[tt.mode], tt.opacity, image.Point{})
+		if got := out.RGBAAt(1, 1); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBlendLayerOffset(t *testing.T) {
+	// Neither the image nor the layer starts at the origin.
+	img := image.NewRGBA(image.Rect(10, 20, 16, 24))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	layer := image.NewRGBA(image.Rect(-5, -5, -2, -3))
+	draw.Draw(layer, layer.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	out := blendLayer(img, layer, BLEND_MODES["normal"], 1, image.Pt(4, 1))
+	if out.Bounds() != img.Bounds() {
+		t.Fatalf("image covers %v, want %v", out.Bounds(), img.Bounds())
+	}
+	// The layer is 3x2, so at (4, 1) only two of its columns are on the image.
+	covered := image.Rect(14, 21, 16, 23)
+	for y := 20; y < 24; y++ {
+		for x := 10; x < 16; x++ {
+			want := uint8(0)
+			if image.Pt(x, y).In(covered) {
+				want = 255
+			}
+			if got := out.RGBAAt(x, y).R; got != want {
+				t.Errorf("(%d, %d) is %d, want %d", x, y, got, want)
+			}
+		}
+	}
+
+	// A layer entirely off the image changes nothing.
+	out = blendLayer(img, layer, BLEND_MODES["normal"], 1, image.Pt(-10, 0))
+	if string(out.Pix) != string(img.Pix) {
+		t.Error("a layer off the image changed it")
+	}
+}
+
+func TestOverlaySnapshot(t *testing.T) {
+	tfms, err := TokensToTfms(SplitCommands("snapshot,name=a,overlay,layer=a,mode=multiply"), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var img image.Image = flatImage(4, 4, color.Gray{128})
+	for _, tfm := range tfms {
+		if img, err = tfm(img); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := img.(*image.RGBA).RGBAAt(0, 0); got != (color.RGBA{64, 64, 64, 255}) {
+		t.Errorf("the image blended over itself is %v, want it multiplied", got)
+	}
+
+	// A snapshot that hasn't been taken when the overlay runs is an error.
+	overlay := OverlayT(nil, newLayerStore(), "a", BLEND_MODES["normal"], 1, image.Point{})
+	if _, err := overlay(flatImage(2, 2, color.White)); err == nil {
+		t.Error("blending a missing snapshot should be an error")
+	}
+}
+
+func TestOverlayArgs(t *testing.T) {
+	tests := []struct {
+		commands string
+		ok       bool
+	}{
+		{"snapshot,name=a,overlay,layer=a", true},
+		{"snapshot,name=a,blur,overlay,layer=a,mode=softlight,opacity=0.5,x=-3,y=4", true},
+		{"overlay,layer=a,snapshot,name=a", false},
+		{"overlay", false},
+		{"snapshot,name=a,overlay,layer=a,file=/does/not/exist.png", false},
+		{"overlay,file=/does/not/exist.png", false},
+		{"snapshot,name=a,overlay,layer=a,mode=burn", false},
+		{"snapshot,name=a,overlay,layer=a,opacity=2", false},
+		{"snapshot,name=a,overlay,layer=a,x=1.5", false},
+		{"snapshot", false},
+		{"snapshot,name=a,size=3", false},
+	}
+	for _, tt := range tests {
+		_, err := TokensToTfms(SplitCommands(tt.commands), 1)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.commands, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: packs.go
+ * Description:
+ *   Sticker packs: directories of images, optionally described by a
+ *   manifest.json with a weight, scale range, anchor and rotation for
+ *   every image.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// The directory of the assets that holds one directory per sticker pack.
+const PACKS_DIR = "packs"
+
+// The pack used by cats and by stickers when no pack is given.
+const DEFAULT_PACK = "cats"
+
+// How many stickers a pack without a manifest places by default.
+var DEFAULT_STICKER_COUNT = [2]int{1, 5}
+
+/*
+ * One image of a sticker pack:
+ *   weight: how likely it is to be picked, relative to the others
+ *   minScale, maxScale: the range its scale is picked from
+ *   anchor: the point of the image, as fractions of its width and height,
+ *     that has to land on the base image when stickers may hang off the
+ *     edge, e.g. {0.5, 1} for the feet of a standing figure
+ *   rotatable: whether it may be turned
+ */
+type packSticker struct {
+	img       image.Image
+	weight    float64
+	minScale  float64
+	maxScale  float64
+	anchor    [2]float64
+	rotatable bool
+}
+
+/*
+ * A set of stickers and the range that the number of stickers placed from
+ * it is picked from.
+ */
+type stickerPack struct {
+	stickers []packSticker
+	minCount int
+	maxCount int
+}
+
+/*
+ * The manifest.json of a pack. Every field except file is optional.
+ */
+type packManifest struct {
+	Count    *[2]int `json:"count"`
+	Stickers []struct {
+		File      string      `json:"file"`
+		Weight    *float64    `json:"weight"`
+		Scale     *[2]float64 `json:"scale"`
+		Anchor    *[2]float64 `json:"anchor"`
+		Rotatable *bool       `json:"rotatable"`
+	} `json:"stickers"`
+}
+
+// Sticker packs that have been loaded, so that every image run in this
+// process shares them. Packs from the assets are kept under "pack:" and
+// packs from other directories under "dir:".
+var stickerPacks = struct {
+	sync.Mutex
+	packs map[string]*stickerPack
+}{packs: make(map[string]*stickerPack)}
+
+/*
+ * Check whether a file name looks like an image that can be a sticker.
+ */
+func isStickerFile(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".png", ".jpg", ".jpeg", ".gif":
+		return true
+	}
+	return false
+}
+
+/*
+ * Decode an image from a filesystem.
+ */
+func decodeFS(fsys fs.FS, name string) (image.Image, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return img, nil
+}
+
+/*
+ * Read the pack in a directory of a filesystem. Without a manifest, every
+ * PNG, JPEG or GIF image in the directory is used with the defaults: a
+ * weight of 1, a scale of 1, a centered anchor and free rotation.
+ */
+func readPack(fsys fs.FS, dir string) (*stickerPack, error) {
+	pack := &stickerPack{make([]packSticker, 0), DEFAULT_STICKER_COUNT[0], DEFAULT_STICKER_COUNT[1]}
+	newSticker := func(img image.Image) packSticker {
+		return packSticker{img, 1, 1, 1, [2]float64{0.5, 0.5}, true}
+	}
+
+	data, err := fs.ReadFile(fsys, path.Join(dir, "manifest.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		entries, err := fs.ReadDir(fsys, dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !isStickerFile(entry.Name()) {
+				continue
+			}
+			img, err := decodeFS(fsys, path.Join(dir, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			pack.stickers = append(pack.stickers, newSticker(img))
+		}
+		if len(pack.stickers) == 0 {
+			return nil, errors.New("no PNG, JPEG or GIF images were found")
+		}
+		return pack, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest packManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("manifest.json: %v", err)
+	}
+	if manifest.Count != nil {
+		pack.minCount, pack.maxCount = manifest.Count[0], manifest.Count[1]
+		if pack.minCount < 1 || pack.maxCount < pack.minCount {
+			return nil, errors.New("manifest.json: count must be a range of at least 1")
+		}
+	}
+	if len(manifest.Stickers) == 0 {
+		return nil, errors.New("manifest.json: no stickers are listed")
+	}
+
+	for _, entry := range manifest.Stickers {
+		img, err := decodeFS(fsys, path.Join(dir, entry.File))
+		if err != nil {
+			return nil, err
+		}
+		sticker := newSticker(img)
+		if entry.Weight != nil {
+			sticker.weight = *entry.Weight
+		}
+		if entry.Scale != nil {
+			sticker.minScale, sticker.maxScale = entry.Scale[0], entry.Scale[1]
+		}
+		if entry.Anchor != nil {
+			sticker.anchor = *entry.Anchor
+		}
+		if entry.Rotatable != nil {
+			sticker.rotatable = *entry.Rotatable
+		}
+
+		if sticker.weight <= 0 {
+			return nil, fmt.Errorf("manifest.json: %s must have a weight greater than 0", entry.File)
+		}
+		if sticker.minScale <= 0 || sticker.maxScale < sticker.minScale {
+			return nil, fmt.Errorf("manifest.json: %s must have a scale range greater than 0", entry.File)
+		}
+		pack.stickers = append(pack.stickers, sticker)
+	}
+	return pack, nil
+}
+
+/*
+ * Get a pack by name from the packs directory of the assets, or from a
+ * directory on disk if dir is given. Each pack is only loaded once per
+ * process.
+ */
+func loadStickerPack(name string, dir string) (*stickerPack, error) {
+	stickerPacks.Lock()
+	defer stickerPacks.Unlock()
+
+	key := "pack:" + name
+	if dir != "" {
+		key = "dir:" + dir
+	}
+	if pack, prs := stickerPacks.packs[key]; prs {
+		return pack, nil
+	}
+
+	var pack *stickerPack
+	var err error
+	if dir != "" {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, err
+		}
+		pack, err = readPack(os.DirFS(dir), ".")
+	} else {
+		if !fs.ValidPath(name) || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("%q is not a valid pack name", name)
+		}
+		pack, err = readPack(assetFS, path.Join(PACKS_DIR, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("there is no pack called %s", name)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	stickerPacks.packs[key] = pack
+	return pack, nil
+}
+
+/*
+ * Pick a sticker from the pack, with more weight meaning more likely.
+ */
+func (p *stickerPack) pick(rng *rand.Rand) packSticker {
+	var total float64
+	for _, sticker := range p.stickers {
+		total += sticker.weight
+	}
+	r := rng.Float64() * total
+	for _, sticker := range p.stickers {
+		r -= sticker.weight
+		if r < 0 {
+			return sticker
+		}
+	}
+	return p.stickers[len(p.stickers)-1]
+}
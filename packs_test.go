@@ -0,0 +1,201 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: packs_test.go
+ * Description:
+ *   Tests for reading sticker packs and picking stickers from them.
+ */
+
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+/*
+ * Encode a small image as a PNG file for a test filesystem.
+ */
+func pngFile(size int) *fstest.MapFile {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, flatImage(size, size, color.White)); err != nil {
+		panic(err)
+	}
+	return &fstest.MapFile{Data: buf.Bytes()}
+}
+
+func TestIsStickerFile(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"cat.png", true},
+		{"CAT.JPG", true},
+		{"cat.jpeg", true},
+		{"cat.gif", true},
+		{"cat.webp", false},
+		{"manifest.json", false},
+		{"png", false},
+	}
+	for _, tt := range tests {
+		if got := isStickerFile(tt.name); got != tt.want {
+			t.Errorf("isStickerFile(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReadPackWithoutManifest(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pack/a.png":       pngFile(2),
+		"pack/b.PNG":       pngFile(3),
+		"pack/notes.txt":   {Data: []byte("not a sticker")},
+		"pack/more/c.png":  pngFile(4),
+		"empty/readme.txt": {Data: []byte("nothing here")},
+	}
+	pack, err := readPack(fsys, "pack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pack.stickers) != 2 {
+		t.Fatalf("pack has %d stickers, want 2", len(pack.stickers))
+	}
+	if pack.minCount != DEFAULT_STICKER_COUNT[0] || pack.maxCount != DEFAULT_STICKER_COUNT[1] {
+		t.Errorf("pack places %d to %d stickers, want the default", pack.minCount, pack.maxCount)
+	}
+	want := packSticker{nil, 1, 1, 1, [2]float64{0.5, 0.5}, true}
+	for _, sticker := range pack.stickers {
+		sticker.img = nil
+		if sticker != want {
+			t.Errorf("sticker has %+v, want the defaults", sticker)
+		}
+	}
+
+	if _, err := readPack(fsys, "empty"); err == nil {
+		t.Error("a directory without images should be an error")
+	}
+}
+
+func TestReadPackManifest(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pack/a.png": pngFile(2),
+		"pack/b.png": pngFile(3),
+		"pack/manifest.json": {Data: []byte(`{
+			"count": [2, 3],
+			"stickers": [
+				{"file": "a.png", "weight": 4, "scale": [0.5, 2], "anchor": [0.5, 1], "rotatable": false},
+				{"file": "b.png"}
+			]
+		}`)},
+	}
+	pack, err := readPack(fsys, "pack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pack.minCount != 2 || pack.maxCount != 3 || len(pack.stickers) != 2 {
+		t.Fatalf("pack places %d to %d of %d stickers", pack.minCount, pack.maxCount, len(pack.stickers))
+	}
+	a, b := pack.stickers[0], pack.stickers[1]
+	if a.img.Bounds().Dx() != 2 || a.weight != 4 || a.minScale != 0.5 || a.maxScale != 2 ||
+		a.anchor != [2]float64{0.5, 1} || a.rotatable {
+		t.Errorf("a.png has %+v", a)
+	}
+	b.img = nil
+	if b != (packSticker{nil, 1, 1, 1, [2]float64{0.5, 0.5}, true}) {
+		t.Errorf("b.png has %+v, want the defaults", b)
+	}
+}
+
+func TestReadPackManifestErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+	}{
+		{"bad json", `{"stickers": [`},
+		{"no stickers", `{"stickers": []}`},
+		{"empty count", `{"count": [0, 2], "stickers": [{"file": "a.png"}]}`},
+		{"backwards count", `{"count": [3, 2], "stickers": [{"file": "a.png"}]}`},
+		{"zero weight", `{"stickers": [{"file": "a.png", "weight": 0}]}`},
+		{"zero scale", `{"stickers": [{"file": "a.png", "scale": [0, 1]}]}`},
+		{"backwards scale", `{"stickers": [{"file": "a.png", "scale": [2, 1]}]}`},
+		{"missing file", `{"stickers": [{"file": "b.png"}]}`},
+		{"not an image", `{"stickers": [{"file": "manifest.json"}]}`},
+	}
+	for _, tt := range tests {
+		fsys := fstest.MapFS{
+			"a.png":         pngFile(2),
+			"manifest.json": {Data: []byte(tt.manifest)},
+		}
+		if _, err := readPack(fsys, "."); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+}
+
+func TestPackPickWeights(t *testing.T) {
+	img := flatImage(1, 1, color.White)
+	pack := &stickerPack{[]packSticker{
+		{img, 1, 1, 1, [2]float64{}, true},
+		{img, 3, 2, 2, [2]float64{}, true},
+		{img, 0.000001, 3, 3, [2]float64{}, true},
+	}, 1, 1}
+
+	rng := rand.New(rand.NewSource(1))
+	counts := make(map[float64]int)
+	const picks = 20000
+	for i := 0; i < picks; i++ {
+		counts[pack.pick(rng).minScale]++
+	}
+	if share := float64(counts[2]) / picks; math.Abs(share-0.75) > 0.02 {
+		t.Errorf("a sticker with 3 of 4 of the weight was picked %.3f of the time", share)
+	}
+	if counts[3] > 2 {
+		t.Errorf("a sticker with almost no weight was picked %d times", counts[3])
+	}
+}
+
+func TestLoadStickerPack(t *testing.T) {
+	cats, err := loadStickerPack(DEFAULT_PACK, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cats.stickers) != 2 || cats.minCount != 1 || cats.maxCount != 5 {
+		t.Errorf("the cats pack has %d stickers, placed %d to %d at a time",
+			len(cats.stickers), cats.minCount, cats.maxCount)
+	}
+	if again, _ := loadStickerPack(DEFAULT_PACK, ""); again != cats {
+		t.Error("the cats pack was loaded twice")
+	}
+
+	dir := t.TempDir()
+	file, err := os.Create(filepath.Join(dir, "dot.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(file, image.NewRGBA(image.Rect(0, 0, 3, 3))); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	pack, err := loadStickerPack("", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pack.stickers) != 1 {
+		t.Errorf("the directory pack has %d stickers, want 1", len(pack.stickers))
+	}
+
+	for _, name := range []string{"dogs", "../cats", "cats/cats"} {
+		if _, err := loadStickerPack(name, ""); err == nil {
+			t.Errorf("loading the %q pack should be an error", name)
+		}
+	}
+	if _, err := loadStickerPack("", filepath.Join(dir, "missing")); err == nil {
+		t.Error("loading a missing directory should be an error")
+	}
+}
@@ -0,0 +1,250 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: palette.go
+ * Description:
+ *   Color palettes: named presets, user given lists and palettes
+ *   generated from an image with median cut.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"math"
+	"sort"
+	"strings"
+)
+
+// The largest palette that can be used, so that palette indices fit in a byte.
+const MAX_PALETTE_SIZE = 256
+
+// The most pixels that are looked at when generating a palette from an image.
+const MAX_PALETTE_SAMPLES = 65536
+
+// Named palettes that can be passed as palette=<name>.
+var PALETTE_PRESETS = map[string]color.Palette{
+	"1bit": {
+		color.RGBA{0x00, 0x00, 0x00, 0xff},
+		color.RGBA{0xff, 0xff, 0xff, 0xff},
+	},
+	// CGA mode 4, palette 1 at high intensity.
+	"cga": {
+		color.RGBA{0x00, 0x00, 0x00, 0xff},
+		color.RGBA{0x55, 0xff, 0xff, 0xff},
+		color.RGBA{0xff, 0x55, 0xff, 0xff},
+		color.RGBA{0xff, 0xff, 0xff, 0xff},
+	},
+	"gameboy": {
+		color.RGBA{0x0f, 0x38, 0x0f, 0xff},
+		color.RGBA{0x30, 0x62, 0x30, 0xff},
+		color.RGBA{0x8b, 0xac, 0x0f, 0xff},
+		color.RGBA{0x9b, 0xbc, 0x0f, 0xff},
+	},
+	"websafe": palette.WebSafe,
+}
+
+/*
+ * A function that picks a palette for an image. Presets and user given
+ * lists ignore the image, while automatic palettes are generated from it.
+ */
+type paletteSource func(img image.Image) color.Palette
+
+/*
+ * Parse a palette given as a preset name, as "auto" to generate one with
+ * numColors colors from the image, or as a list of colors separated by
+ * colons, e.g. "#000:#f00:#fff".
+ */
+func parsePalette(spec string, numColors int) (paletteSource, error) {
+	name := strings.ToLower(strings.ReplaceAll(spec, "-", ""))
+	if preset, prs := PALETTE_PRESETS[name]; prs {
+		return func(image.Image) color.Palette { return preset }, nil
+	}
+
+	if name == "auto" {
+		if numColors < 2 || numColors > MAX_PALETTE_SIZE {
+			return nil, fmt.Errorf("colors must be between 2 and %d", MAX_PALETTE_SIZE)
+		}
+		return func(img image.Image) color.Palette {
+			return medianCut(samplePixels(img, MAX_PALETTE_SAMPLES), numColors)
+		}, nil
+	}
+
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 || len(parts) > MAX_PALETTE_SIZE {
+		return nil, fmt.Errorf("palette must be a preset, auto, or 2 to %d colors", MAX_PALETTE_SIZE)
+	}
+	pal := make(color.Palette, 0, len(parts))
+	for _, part := range parts {
+		c, err := parseColor(part)
+		if err != nil {
+			return nil, err
+		}
+		pal = append(pal, c)
+	}
+	return func(image.Image) color.Palette { return pal }, nil
+}
+
+/*
+ * Take an evenly spaced sample of at most maxSamples opaque pixels from
+ * an image. Rows are sampled in parallel.
+ */
+func samplePixels(img image.Image, maxSamples int) [][3]uint8 {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return nil
+	}
+
+	step := 1
+	if width*height > maxSamples {
+		step = int(math.Ceil(math.Sqrt(float64(width*height) / float64(maxSamples))))
+	}
+	cols := (width + step - 1) / step
+	rows := (height + step - 1) / step
+
+	samples := make([][3]uint8, cols*rows)
+	opaque := make([]bool, cols*rows)
+
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(0, rows, func(lo int, hi int) {
+		for ry := lo; ry < hi; ry++ {
+			y := bounds.Min.Y + ry*step
+			for rx := 0; rx < cols; rx++ {
+				r, g, b, a := img.At(bounds.Min.X+rx*step, y).RGBA()
+				if a < 0x8000 {
+					continue
+				}
+				samples[ry*cols+rx] = [3]uint8{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8)}
+				opaque[ry*cols+rx] = true
+			}
+		}
+	})
+
+	kept := samples[:0]
+	for i, s := range samples {
+		if opaque[i] {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
+
+/*
+ * A box of pixels in RGB space used by median cut.
+ */
+type colorBox struct {
+	pixels [][3]uint8
+}
+
+/*
+ * Get the channel with the widest spread in the box and its spread.
+ */
+func (b colorBox) widestChannel() (int, int) {
+	lo := [3]uint8{255, 255, 255}
+	hi := [3]uint8{0, 0, 0}
+	for _, p := range b.pixels {
+		for c := 0; c < 3; c++ {
+			lo[c] = min(lo[c], p[c])
+			hi[c] = max(hi[c], p[c])
+		}
+	}
+	channel := 0
+	for c := 1; c < 3; c++ {
+		if int(hi[c])-int(lo[c]) > int(hi[channel])-int(lo[channel]) {
+			channel = c
+		}
+	}
+	return channel, int(hi[channel]) - int(lo[channel])
+}
+
+/*
+ * Get the average color of the box.
+ */
+func (b colorBox) average() color.RGBA {
+	var sum [3]int
+	for _, p := range b.pixels {
+		sum[0] += int(p[0])
+		sum[1] += int(p[1])
+		sum[2] += int(p[2])
+	}
+	n := len(b.pixels)
+	return color.RGBA{uint8(sum[0] / n), uint8(sum[1] / n), uint8(sum[2] / n), 255}
+}
+
+/*
+ * Generate a palette of at most numColors colors with median cut: keep
+ * splitting the box with the widest channel at its median until there are
+ * enough boxes, and then use the average color of each box.
+ */
+func medianCut(pixels [][3]uint8, numColors int) color.Palette {
+	if len(pixels) == 0 {
+		return color.Palette{color.RGBA{0, 0, 0, 255}}
+	}
+
+	// Work on a copy, since boxes are sorted in place.
+	boxes := []colorBox{{append([][3]uint8(nil), pixels...)}}
+	for len(boxes) < numColors {
+		best, bestSpread, bestChannel := -1, 0, 0
+		for i, box := range boxes {
+			if len(box.pixels) < 2 {
+				continue
+			}
+			channel, spread := box.widestChannel()
+			if spread > bestSpread {
+				best, bestSpread, bestChannel = i, spread, channel
+			}
+		}
+		if best < 0 {
+			// Every box holds a single color, so no more splits are useful.
+			break
+		}
+
+		box := boxes[best]
+		sort.Slice(box.pixels, func(i, j int) bool {
+			return box.pixels[i][bestChannel] < box.pixels[j][bestChannel]
+		})
+		mid := len(box.pixels) / 2
+		boxes[best] = colorBox{box.pixels[:mid]}
+		boxes = append(boxes, colorBox{box.pixels[mid:]})
+	}
+
+	pal := make(color.Palette, len(boxes))
+	for i, box := range boxes {
+		pal[i] = box.average()
+	}
+	return pal
+}
+
+/*
+ * Convert a palette into plain RGB values for fast nearest color lookups.
+ */
+func paletteRGB(pal color.Palette) ([][3]float64, error) {
+	if len(pal) == 0 {
+		return nil, errors.New("palette is empty")
+	}
+	rgb := make([][3]float64, len(pal))
+	for i, c := range pal {
+		r, g, b, _ := c.RGBA()
+		rgb[i] = [3]float64{float64(r >> 8), float64(g >> 8), float64(b >> 8)}
+	}
+	return rgb, nil
+}
+
+/*
+ * Get the index of the palette color closest to the given color.
+ */
+func nearestColor(rgb [][3]float64, r, g, b float64) int {
+	best := 0
+	bestDist := math.Inf(1)
+	for i, p := range rgb {
+		dist := colorDistSq(r, g, b, p[0], p[1], p[2])
+		if dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
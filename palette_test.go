@@ -0,0 +1,110 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: palette_test.go
+ * Description:
+ *   Tests for parsing colors and palettes and for median cut.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		in   string
+		want color.NRGBA
+		ok   bool
+	}{
+		{"black", color.NRGBA{0, 0, 0, 255}, true},
+		{" White ", color.NRGBA{255, 255, 255, 255}, true},
+		{"transparent", color.NRGBA{0, 0, 0, 0}, true},
+		{"#f80", color.NRGBA{0xff, 0x88, 0x00, 0xff}, true},
+		{"#12ab34", color.NRGBA{0x12, 0xab, 0x34, 0xff}, true},
+		{"12AB3480", color.NRGBA{0x12, 0xab, 0x34, 0x80}, true},
+		{"#12ab3", color.NRGBA{}, false},
+		{"#ggg", color.NRGBA{}, false},
+		{"mauve", color.NRGBA{}, false},
+	}
+	for _, tt := range tests {
+		got, err := parseColor(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseColor(%q) = %v, %v, want %v, ok %v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParsePalette(t *testing.T) {
+	tests := []struct {
+		spec      string
+		numColors int
+		size      int
+		ok        bool
+	}{
+		{"1bit", 0, 2, true},
+		{"GameBoy", 0, 4, true},
+		{"web-safe", 0, 216, true},
+		{"#000:#f00:#fff", 0, 3, true},
+		{"auto", 2, 2, true},
+		{"auto", 1, 0, false},
+		{"auto", MAX_PALETTE_SIZE + 1, 0, false},
+		{"#000", 0, 0, false},
+		{"#000:nope", 0, 0, false},
+	}
+	img := gradientImage(32, 32)
+	for _, tt := range tests {
+		source, err := parsePalette(tt.spec, tt.numColors)
+		if (err == nil) != tt.ok {
+			t.Errorf("parsePalette(%q, %d) error %v, want ok %v", tt.spec, tt.numColors, err, tt.ok)
+			continue
+		}
+		if err == nil && len(source(img)) != tt.size {
+			t.Errorf("parsePalette(%q, %d) has %d colors, want %d", tt.spec, tt.numColors, len(source(img)), tt.size)
+		}
+	}
+}
+
+func TestMedianCutFindsClusters(t *testing.T) {
+	pixels := make([][3]uint8, 0)
+	for i := 0; i < 50; i++ {
+		pixels = append(pixels, [3]uint8{10, 20, 30}, [3]uint8{200, 210, 220})
+	}
+	pal := medianCut(pixels, 2)
+	if len(pal) != 2 {
+		t.Fatalf("medianCut made %d colors, want 2", len(pal))
+	}
+	want := map[color.Color]bool{
+		color.RGBA{10, 20, 30, 255}:    true,
+		color.RGBA{200, 210, 220, 255}: true,
+	}
+	for _, c := range pal {
+		if !want[c] {
+			t.Errorf("medianCut made %v, want one of the two clusters", c)
+		}
+	}
+}
+
+func TestMedianCutStopsAtOneColor(t *testing.T) {
+	pixels := [][3]uint8{{1, 2, 3}, {1, 2, 3}, {1, 2, 3}}
+	if pal := medianCut(pixels, 16); len(pal) != 1 {
+		t.Errorf("medianCut of a single color made %d colors, want 1", len(pal))
+	}
+	if pal := medianCut(nil, 16); len(pal) != 1 {
+		t.Errorf("medianCut of no pixels made %d colors, want 1", len(pal))
+	}
+}
+
+func TestSamplePixelsSkipsTransparent(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	img.SetNRGBA(1, 1, color.NRGBA{9, 8, 7, 255})
+	samples := samplePixels(img, 1000)
+	if len(samples) != 1 || samples[0] != [3]uint8{9, 8, 7} {
+		t.Errorf("samplePixels = %v, want only the one opaque pixel", samples)
+	}
+	if n := len(samplePixels(gradientImage(100, 100), 100)); n > 100 {
+		t.Errorf("samplePixels took %d samples, want at most 100", n)
+	}
+}
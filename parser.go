@@ -12,22 +12,230 @@ import (
     "fmt"
     "image"
     "strconv"
+    "strings"
 )
 
+/*
+ * Split a command string into tokens at every comma. A comma that follows
+ * a backslash, as in "text=(c) ACME\, Inc.", is kept as part of its token.
+ */
+func SplitCommands(commands string) []string {
+    tokens := make([]string, 0)
+    var token strings.Builder
+    for i := 0; i < len(commands); i++ {
+        switch {
+        case commands[i] == '\\' && i+1 < len(commands) && commands[i+1] == ',':
+            token.WriteByte(',')
+            i++
+        case commands[i] == ',':
+            tokens = append(tokens, token.String())
+            token.Reset()
+        default:
+            token.WriteByte(commands[i])
+        }
+    }
+    return append(tokens, token.String())
+}
+
+/*
+ * Named arguments given to a transformation as key=value tokens, e.g.
+ * "dither,method=atkinson,palette=gameboy". A key may be given more than
+ * once, in which case every value is kept in order.
+ */
+type tfmArgs map[string][]string
+
+/*
+ * Collect the key=value tokens that directly follow the token at index i.
+ *
+ * Returns: The collected arguments and the index of the last token consumed.
+ */
+func collectArgs(tokens []string, i int) (tfmArgs, int) {
+    args := make(tfmArgs)
+    for i+1 < len(tokens) {
+        key, value, found := strings.Cut(tokens[i+1], "=")
+	if !found {
+            break
+	}
+	key = strings.ToLower(strings.TrimSpace(key))
+	args[key] = append(args[key], strings.TrimSpace(value))
+	i++
+    }
+    return args, i
+}
+
+/*
+ * Make sure that only the given keys were passed to a transformation.
+ */
+func (a tfmArgs) allow(keys ...string) error {
+    for key := range a {
+        known := false
+	for _, k := range keys {
+            if k == key {
+                known = true
+		break
+	    }
+	}
+	if !known {
+            return fmt.Errorf("unknown argument %q", key)
+	}
+    }
+    return nil
+}
+
+/*
+ * Whether an argument was given.
+ */
+func (a tfmArgs) has(key string) bool {
+    _, prs := a[key]
+    return prs
+}
+
+/*
+ * Get the last value given for a key, or def if the key was not given.
+ */
+func (a tfmArgs) getString(key string, def string) string {
+    values, prs := a[key]
+    if !prs || len(values) == 0 {
+        return def
+    }
+    return values[len(values)-1]
+}
+
+/*
+ * Get an argument as a floating point number.
+ */
+func (a tfmArgs) getFloat(key string, def float64) (float64, error) {
+    if !a.has(key) {
+        return def, nil
+    }
+    value, err := strconv.ParseFloat(a.getString(key, ""), 64)
+    if err != nil {
+        return 0, fmt.Errorf("%s must be a number", key)
+    }
+    return value, nil
+}
+
+/*
+ * Get an argument as an integer.
+ */
+func (a tfmArgs) getInt(key string, def int) (int, error) {
+    if !a.has(key) {
+        return def, nil
+    }
+    value, err := strconv.Atoi(a.getString(key, ""))
+    if err != nil {
+        return 0, fmt.Errorf("%s must be an integer", key)
+    }
+    return value, nil
+}
+
+/*
+ * Get an argument as a boolean. A key given without a value, as in
+ * "mono=", counts as true.
+ */
+func (a tfmArgs) getBool(key string, def bool) (bool, error) {
+    if !a.has(key) {
+        return def, nil
+    }
+    value := a.getString(key, "")
+    if value == "" {
+        return true, nil
+    }
+    b, err := strconv.ParseBool(value)
+    if err != nil {
+        return false, fmt.Errorf("%s must be true or false", key)
+    }
+    return b, nil
+}
+
+/*
+ * Get an argument as a seed for random numbers.
+ */
+func (a tfmArgs) getSeed(def int64) (int64, error) {
+    if !a.has("seed") {
+        return def, nil
+    }
+    value, err := strconv.ParseInt(a.getString("seed", ""), 10, 64)
+    if err != nil {
+        return 0, errors.New("seed must be an integer")
+    }
+    return value, nil
+}
+
+/*
+ * Get every value of an argument as a rectangle written as x:y:w:h.
+ */
+func (a tfmArgs) getRects(key string) ([]image.Rectangle, error) {
+    rects := make([]image.Rectangle, 0)
+    for _, value := range a[key] {
+        parts := strings.Split(value, ":")
+	if len(parts) != 4 {
+            return nil, fmt.Errorf("%s must be written as x:y:w:h", key)
+	}
+	var nums [4]int
+	for j, part := range parts {
+            n, err := strconv.Atoi(part)
+	    if err != nil {
+                return nil, fmt.Errorf("%s must be written as x:y:w:h", key)
+	    }
+	    nums[j] = n
+	}
+	if nums[2] <= 0 || nums[3] <= 0 {
+            return nil, fmt.Errorf("%s must have a positive width and height", key)
+	}
+	rects = append(rects, image.Rect(nums[0], nums[1], nums[0]+nums[2], nums[1]+nums[3]))
+    }
+    return rects, nil
+}
+
+/*
+ * Get an argument as a list of exactly n numbers written as a:b:c...
+ */
+func (a tfmArgs) getFloats(key string, n int) ([]float64, error) {
+    parts := strings.Split(a.getString(key, ""), ":")
+    if len(parts) != n {
+        return nil, fmt.Errorf("%s must be %d numbers separated by colons", key, n)
+    }
+    nums := make([]float64, n)
+    for j, part := range parts {
+        num, err := strconv.ParseFloat(part, 64)
+	if err != nil {
+            return nil, fmt.Errorf("%s must be %d numbers separated by colons", key, n)
+	}
+	nums[j] = num
+    }
+    return nums, nil
+}
+
 /*
  * Convert tokens into transformation functions.
  *
- * Parameter:
+ * Parameters:
  *   tokens: The tokens to convert
+ *   seed: The seed that random transformations get their seeds from
  *
  * Returns: An array of transformation functions or an error.
  */
-func TokensToTfms(tokens []string) ([]func(img image.Image) (image.Image, error), error) {
+func TokensToTfms(tokens []string, seed int64) ([]func(img image.Image) (image.Image, error), error) {
     lenTkns := len(tokens)
     tfms := make([]func(image.Image) (image.Image, error), 0)
 
+    // Every random transformation gets its own seed, so that two of them
+    // don't make the same choices. A seed= argument takes its place, but
+    // the transformations after it keep theirs.
+    numSeeds := uint64(0)
+    nextSeed := func() int64 {
+        numSeeds++
+        return int64(mix64(uint64(seed) + numSeeds))
+    }
+
+    // Snapshots of the image that later overlays can blend in.
+    layers := newLayerStore()
+
     for i := 0; i < lenTkns; i++ {
         switch tokens[i] {
+	    case "":
+		// No transformation, e.g. when only converting to ASCII art.
 	    case "blur":
                 tfms = append(tfms, BlurParallel)
 	    case "grayscale":
@@ -42,7 +250,334 @@ func TokensToTfms(tokens []string) ([]func(img image.Image) (image.Image, error)
 		i++
 		tfms = append(tfms, ResizeT(scalar))
 	    case "cats":
-	        tfms = append(tfms, CatImages)
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := catsFromArgs(args, nextSeed())
+		if err != nil {
+                    return nil, fmt.Errorf("cats: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "dither":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := ditherFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("dither: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "quantize":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := quantizeFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("quantize: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "edges":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := edgesFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("edges: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "canny":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := cannyFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("canny: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "threshold":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := thresholdFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("threshold: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "adaptivethreshold":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := adaptiveThresholdFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("adaptivethreshold: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "median":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := medianFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("median: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "bilateral":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := bilateralFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("bilateral: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "nlmeans":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := nlmeansFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("nlmeans: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "erode", "dilate", "open", "close", "tophat", "morphgradient":
+		op := tokens[i]
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := morphFromArgs(op)(args)
+		if err != nil {
+                    return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		tfms = append(tfms, tfm)
+	    case "pixelate":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := pixelateFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("pixelate: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "blurregion":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := blurRegionFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("blurregion: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "vignette":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := vignetteFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("vignette: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "grain":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := grainFromArgs(args, nextSeed())
+		if err != nil {
+                    return nil, fmt.Errorf("grain: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "lightleak":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := lightLeakFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("lightleak: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "posterize":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := posterizeFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("posterize: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "oilpaint":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := oilPaintFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("oilpaint: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "cartoon":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := cartoonFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("cartoon: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "sketch":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := sketchFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("sketch: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "halftone":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := halftoneFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("halftone: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "swirl":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := swirlFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("swirl: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "wave":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := waveFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("wave: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "fisheye":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := fisheyeFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("fisheye: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "barrel":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := barrelFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("barrel: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "polar":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := polarFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("polar: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "cartesian":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := cartesianFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("cartesian: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "affine":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := affineFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("affine: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "perspective":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := perspectiveFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("perspective: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "carve":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := carveFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("carve: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "pad":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := padFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("pad: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "canvas":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := canvasFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("canvas: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "border":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := borderFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("border: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "stickers":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := stickersFromArgs(args, nextSeed())
+		if err != nil {
+                    return nil, fmt.Errorf("stickers: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "watermark":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := watermarkFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("watermark: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "text":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := textFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("text: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "snapshot":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := snapshotFromArgs(args, layers)
+		if err != nil {
+                    return nil, fmt.Errorf("snapshot: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "overlay":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := overlayFromArgs(args, layers)
+		if err != nil {
+                    return nil, fmt.Errorf("overlay: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "roundcorners":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := roundCornersFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("roundcorners: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "shadow":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := shadowFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("shadow: %v", err)
+		}
+		tfms = append(tfms, tfm)
+	    case "glow":
+		args, last := collectArgs(tokens, i)
+		i = last
+		tfm, err := glowFromArgs(args)
+		if err != nil {
+                    return nil, fmt.Errorf("glow: %v", err)
+		}
+		tfms = append(tfms, tfm)
 	    default:
 		return nil, errors.New(fmt.Sprintf("%s is not a valid option", tokens[i]))
 	}
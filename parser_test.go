@@ -0,0 +1,213 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: parser_test.go
+ * Description:
+ *   Tests for the command line argument parser.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommands(t *testing.T) {
+	tests := []struct {
+		commands string
+		want     []string
+	}{
+		{"a,b,c", []string{"a", "b", "c"}},
+		{`a\,b,c`, []string{"a,b", "c"}},
+		{`text=x\, y\, z`, []string{"text=x, y, z"}},
+		{`a\b`, []string{`a\b`}},
+		{`a\`, []string{`a\`}},
+		{"a,,b", []string{"a", "", "b"}},
+		{"a,", []string{"a", ""}},
+		{"", []string{""}},
+	}
+	for _, tt := range tests {
+		if got := SplitCommands(tt.commands); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitCommands(%q) = %q, want %q", tt.commands, got, tt.want)
+		}
+	}
+}
+
+func TestCollectArgs(t *testing.T) {
+	tokens := []string{"blur", " Radius = 3 ", "keepout=0:0:1:1", "keepout=2:2:1:1", "grain", "amount=5"}
+	args, last := collectArgs(tokens, 0)
+	want := tfmArgs{"radius": {"3"}, "keepout": {"0:0:1:1", "2:2:1:1"}}
+	if !reflect.DeepEqual(args, want) || last != 3 {
+		t.Errorf("collectArgs = %v, %d, want %v, 3", args, last, want)
+	}
+	if args, last := collectArgs(tokens, 4); len(args) != 1 || last != 5 {
+		t.Errorf("collectArgs at the end = %v, %d", args, last)
+	}
+}
+
+func TestArgsAllow(t *testing.T) {
+	args := tfmArgs{"radius": {"3"}, "sigma": {"1"}}
+	if err := args.allow("radius", "sigma", "mode"); err != nil {
+		t.Error(err)
+	}
+	if err := args.allow("radius"); err == nil {
+		t.Error("an argument that isn't allowed was accepted")
+	}
+	if err := (tfmArgs{}).allow(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestArgsGetters(t *testing.T) {
+	args := tfmArgs{
+		"n":     {"1", "2"},
+		"x":     {"1.5"},
+		"word":  {"hello"},
+		"on":    {""},
+		"off":   {"false"},
+		"pair":  {"1:-2.5"},
+		"three": {"1:2"},
+		"rect":  {"1:2:3:4", "-5:0:1:1"},
+		"flat":  {"0:0:3:0"},
+	}
+
+	ints := []struct {
+		key  string
+		want int
+		ok   bool
+	}{
+		{"n", 2, true},
+		{"missing", 7, true},
+		{"x", 0, false},
+		{"word", 0, false},
+	}
+	for _, tt := range ints {
+		got, err := args.getInt(tt.key, 7)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("getInt(%s) = %d, %v, want %d and ok %v", tt.key, got, err, tt.want, tt.ok)
+		}
+	}
+
+	floats := []struct {
+		key  string
+		want float64
+		ok   bool
+	}{
+		{"x", 1.5, true},
+		{"n", 2, true},
+		{"missing", 0.25, true},
+		{"word", 0, false},
+	}
+	for _, tt := range floats {
+		got, err := args.getFloat(tt.key, 0.25)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("getFloat(%s) = %v, %v, want %v and ok %v", tt.key, got, err, tt.want, tt.ok)
+		}
+	}
+
+	bools := []struct {
+		key  string
+		def  bool
+		want bool
+		ok   bool
+	}{
+		{"on", false, true, true},
+		{"off", true, false, true},
+		{"missing", true, true, true},
+		{"word", false, false, false},
+	}
+	for _, tt := range bools {
+		got, err := args.getBool(tt.key, tt.def)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("getBool(%s) = %v, %v, want %v and ok %v", tt.key, got, err, tt.want, tt.ok)
+		}
+	}
+
+	if got := args.getString("n", ""); got != "2" {
+		t.Errorf("getString(n) = %q, want the last value", got)
+	}
+	if got, err := args.getFloats("pair", 2); err != nil || !reflect.DeepEqual(got, []float64{1, -2.5}) {
+		t.Errorf("getFloats(pair) = %v, %v", got, err)
+	}
+	for _, key := range []string{"three", "word", "missing"} {
+		if _, err := args.getFloats(key, 3); err == nil {
+			t.Errorf("getFloats(%s) is not three numbers", key)
+		}
+	}
+
+	rects, err := args.getRects("rect")
+	want := []image.Rectangle{image.Rect(1, 2, 4, 6), image.Rect(-5, 0, -4, 1)}
+	if err != nil || !reflect.DeepEqual(rects, want) {
+		t.Errorf("getRects(rect) = %v, %v, want %v", rects, err, want)
+	}
+	if rects, err := args.getRects("missing"); err != nil || len(rects) != 0 {
+		t.Errorf("getRects(missing) = %v, %v", rects, err)
+	}
+	for _, key := range []string{"flat", "pair", "word"} {
+		if _, err := args.getRects(key); err == nil {
+			t.Errorf("getRects(%s) is not a rectangle", key)
+		}
+	}
+}
+
+func TestGetSeed(t *testing.T) {
+	tests := []struct {
+		args tfmArgs
+		want int64
+		ok   bool
+	}{
+		{tfmArgs{}, 42, true},
+		{tfmArgs{"seed": {"7"}}, 7, true},
+		{tfmArgs{"seed": {"-9223372036854775808"}}, -9223372036854775808, true},
+		{tfmArgs{"seed": {"1.5"}}, 0, false},
+		{tfmArgs{"seed": {"lucky"}}, 0, false},
+	}
+	for _, tt := range tests {
+		got, err := tt.args.getSeed(42)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("getSeed(%v) = %d, %v, want %d and ok %v", tt.args, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+/*
+ * Run the transformation at index i of a command string on a gray image.
+ */
+func runCommand(t *testing.T, commands string, seed int64, i int) []uint8 {
+	t.Helper()
+	tfms, err := TokensToTfms(SplitCommands(commands), seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := tfms[i](flatImage(16, 16, color.Gray{128}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out.(*image.RGBA).Pix
+}
+
+func TestTransformationSeeds(t *testing.T) {
+	same := func(a []uint8, b []uint8) bool { return string(a) == string(b) }
+
+	if !same(runCommand(t, "grain", 1, 0), runCommand(t, "grain", 1, 0)) {
+		t.Error("the same seed gave different grain")
+	}
+	if same(runCommand(t, "grain", 1, 0), runCommand(t, "grain", 2, 0)) {
+		t.Error("different seeds gave the same grain")
+	}
+	if same(runCommand(t, "grain,grain", 1, 0), runCommand(t, "grain,grain", 1, 1)) {
+		t.Error("two grains in one pipeline got the same seed")
+	}
+	if !same(runCommand(t, "grain,seed=5", 1, 0), runCommand(t, "grain,seed=5", 2, 0)) {
+		t.Error("seed= did not take the place of the pipeline's seed")
+	}
+	// A seed= argument doesn't move the seeds of the transformations after it.
+	if !same(runCommand(t, "grain,seed=5,grain", 1, 1), runCommand(t, "grain,grain", 1, 1)) {
+		t.Error("seed= changed the seed of the next transformation")
+	}
+	if !same(runCommand(t, "cats,seed=3", 1, 0), runCommand(t, "cats,seed=3", 9, 0)) {
+		t.Error("cats with the same seed= placed different cats")
+	}
+}
@@ -0,0 +1,256 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: perspective.go
+ * Description:
+ *   Affine and perspective transforms, e.g. for straightening photos of
+ *   documents and whiteboards.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+/*
+ * A 3x3 projective matrix, row by row, that maps (x, y) to
+ * ((m0 x + m1 y + m2) / w, (m3 x + m4 y + m5) / w) where
+ * w = m6 x + m7 y + m8.
+ */
+type homography [9]float64
+
+/*
+ * Map a point with the matrix.
+ *
+ * Returns: The mapped point, and false if the point maps to infinity or
+ * lands behind the viewer.
+ */
+func (h homography) apply(x float64, y float64) (float64, float64, bool) {
+	w := h[6]*x + h[7]*y + h[8]
+	if w <= 1e-12 {
+		return 0, 0, false
+	}
+	return (h[0]*x + h[1]*y + h[2]) / w, (h[3]*x + h[4]*y + h[5]) / w, true
+}
+
+/*
+ * Invert the matrix with its adjugate.
+ */
+func (h homography) invert() (homography, error) {
+	inv := homography{
+		h[4]*h[8] - h[5]*h[7], h[2]*h[7] - h[1]*h[8], h[1]*h[5] - h[2]*h[4],
+		h[5]*h[6] - h[3]*h[8], h[0]*h[8] - h[2]*h[6], h[2]*h[3] - h[0]*h[5],
+		h[3]*h[7] - h[4]*h[6], h[1]*h[6] - h[0]*h[7], h[0]*h[4] - h[1]*h[3],
+	}
+	det := h[0]*inv[0] + h[1]*inv[3] + h[2]*inv[6]
+	if math.Abs(det) < 1e-12 {
+		return homography{}, errors.New("matrix can't be inverted")
+	}
+	for i := range inv {
+		inv[i] /= det
+	}
+	return inv, nil
+}
+
+/*
+ * Find the homography that maps four points onto four other points by
+ * solving the 8x8 linear system with Gaussian elimination. Points are
+ * given as x0, y0, x1, y1, ...
+ */
+func solveHomography(from []float64, to []float64) (homography, error) {
+	var system [8][9]float64
+	for i := 0; i < 4; i++ {
+		u, v := from[2*i], from[2*i+1]
+		x, y := to[2*i], to[2*i+1]
+		system[2*i] = [9]float64{u, v, 1, 0, 0, 0, -u * x, -v * x, x}
+		system[2*i+1] = [9]float64{0, 0, 0, u, v, 1, -u * y, -v * y, y}
+	}
+
+	for col := 0; col < 8; col++ {
+		// Pick the largest pivot to keep the solution stable.
+		pivot := col
+		for row := col + 1; row < 8; row++ {
+			if math.Abs(system[row][col]) > math.Abs(system[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(system[pivot][col]) < 1e-12 {
+			return homography{}, errors.New("no three points may be on one line")
+		}
+		system[col], system[pivot] = system[pivot], system[col]
+
+		for row := 0; row < 8; row++ {
+			if row == col {
+				continue
+			}
+			f := system[row][col] / system[col][col]
+			for k := col; k < 9; k++ {
+				system[row][k] -= f * system[col][k]
+			}
+		}
+	}
+
+	var h homography
+	for i := 0; i < 8; i++ {
+		h[i] = system[i][8] / system[i][i]
+	}
+	h[8] = 1
+	return h, nil
+}
+
+/*
+ * Get a function that will warp any image with a projective matrix that
+ * maps output points to source points, into an image of the given size.
+ */
+func projectT(inverse homography, width int, height int, filter resampleFilter, background color.NRGBA) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		w, h := width, height
+		if w == 0 {
+			w = img.Bounds().Dx()
+		}
+		if h == 0 {
+			h = img.Bounds().Dy()
+		}
+		origin := img.Bounds().Min
+		// The matrix works in coordinates relative to the image's corner.
+		mapping := func(x float64, y float64) (float64, float64, bool) {
+			sx, sy, ok := inverse.apply(x, y)
+			return sx + float64(origin.X), sy + float64(origin.Y), ok
+		}
+		return warpImage(img, image.Rect(0, 0, w, h), mapping, filter, background), nil
+	}
+}
+
+/*
+ * Get a function that will apply the affine matrix
+ *   | a b c |
+ *   | d e f |
+ * to any image, so that the source point (x, y) lands on
+ * (a x + b y + c, d x + e y + f). A width or height of 0 keeps the size
+ * of the image.
+ */
+func AffineT(matrix [6]float64, width int, height int, filter resampleFilter, background color.NRGBA) (func(image.Image) (image.Image, error), error) {
+	forward := homography{matrix[0], matrix[1], matrix[2], matrix[3], matrix[4], matrix[5], 0, 0, 1}
+	inverse, err := forward.invert()
+	if err != nil {
+		return nil, err
+	}
+	return projectT(inverse, width, height, filter, background), nil
+}
+
+/*
+ * Get a function that will move the four src corners of any image onto
+ * the four dst corners, with everything in between following the
+ * perspective. Corners are given as x0, y0, ..., x3, y3. A width or height
+ * of 0 keeps the size of the image.
+ */
+func PerspectiveT(src []float64, dst []float64, width int, height int, filter resampleFilter, background color.NRGBA) (func(image.Image) (image.Image, error), error) {
+	inverse, err := solveHomography(dst, src)
+	if err != nil {
+		return nil, err
+	}
+	return projectT(inverse, width, height, filter, background), nil
+}
+
+/*
+ * Read the output size, resampling filter and background color shared by
+ * affine and perspective.
+ */
+func projectArgs(args tfmArgs) (int, int, resampleFilter, color.NRGBA, error) {
+	width, err := args.getInt("width", 0)
+	if err != nil {
+		return 0, 0, nil, color.NRGBA{}, err
+	}
+	height, err := args.getInt("height", 0)
+	if err != nil {
+		return 0, 0, nil, color.NRGBA{}, err
+	}
+	if width < 0 || height < 0 {
+		return 0, 0, nil, color.NRGBA{}, errors.New("width and height must not be negative")
+	}
+
+	name := args.getString("filter", "bilinear")
+	filter, prs := RESAMPLE_FILTERS[name]
+	if !prs {
+		return 0, 0, nil, color.NRGBA{}, fmt.Errorf("%s is not a valid filter", name)
+	}
+	background, err := warpBackground(args)
+	if err != nil {
+		return 0, 0, nil, color.NRGBA{}, err
+	}
+	return width, height, filter, background, nil
+}
+
+/*
+ * Build an affine transformation from its command line arguments.
+ */
+func affineFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("matrix", "width", "height", "filter", "background"); err != nil {
+		return nil, err
+	}
+
+	if !args.has("matrix") {
+		return nil, errors.New("matrix is required")
+	}
+	nums, err := args.getFloats("matrix", 6)
+	if err != nil {
+		return nil, err
+	}
+	width, height, filter, background, err := projectArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	return AffineT([6]float64(nums), width, height, filter, background)
+}
+
+/*
+ * Build a perspective transformation from its command line arguments.
+ * Without dst, the src corners, given clockwise from the top left, are
+ * straightened into a rectangle. Its size is width by height, or the
+ * average lengths of the sides of the src corners if those aren't given.
+ */
+func perspectiveFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("src", "dst", "width", "height", "filter", "background"); err != nil {
+		return nil, err
+	}
+
+	if !args.has("src") {
+		return nil, errors.New("src is required")
+	}
+	src, err := args.getFloats("src", 8)
+	if err != nil {
+		return nil, err
+	}
+	width, height, filter, background, err := projectArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	var dst []float64
+	if args.has("dst") {
+		dst, err = args.getFloats("dst", 8)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		side := func(i int, j int) float64 {
+			return math.Hypot(src[2*j]-src[2*i], src[2*j+1]-src[2*i+1])
+		}
+		if width == 0 {
+			width = int(math.Round((side(0, 1) + side(3, 2)) / 2))
+		}
+		if height == 0 {
+			height = int(math.Round((side(0, 3) + side(1, 2)) / 2))
+		}
+		if width == 0 || height == 0 {
+			return nil, errors.New("src corners must not overlap")
+		}
+		w, h := float64(width), float64(height)
+		dst = []float64{0, 0, w, 0, w, h, 0, h}
+	}
+	return PerspectiveT(src, dst, width, height, filter, background)
+}
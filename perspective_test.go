@@ -0,0 +1,185 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: perspective_test.go
+ * Description:
+ *   Tests for homographies and the affine and perspective transforms.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestSolveHomographyRoundTrip(t *testing.T) {
+	// Convex quads, clockwise from the top left.
+	quads := [][]float64{
+		{0, 0, 10, 0, 10, 10, 0, 10},
+		{0, 0, 100, 0, 100, 50, 0, 50},
+		{12, 7, 90, 20, 80, 70, 5, 60},
+		{40, 10, 60, 10, 100, 90, 0, 90},
+		{-3.5, 2.25, 17, -8, 21.5, 30, 1, 12},
+		{0, 0, 640, 0, 640, 480, 0, 480},
+	}
+	for i := 0; i+1 < len(quads); i++ {
+		from, to := quads[i], quads[i+1]
+		h, err := solveHomography(from, to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inv, err := h.invert()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for p := 0; p < 4; p++ {
+			x, y, ok := h.apply(from[2*p], from[2*p+1])
+			if !ok || math.Abs(x-to[2*p]) > 1e-6 || math.Abs(y-to[2*p+1]) > 1e-6 {
+				t.Errorf("%v to %v: corner %d maps to (%v, %v), want (%v, %v)",
+					from, to, p, x, y, to[2*p], to[2*p+1])
+			}
+			bx, by, ok := inv.apply(x, y)
+			if !ok || math.Abs(bx-from[2*p]) > 1e-6 || math.Abs(by-from[2*p+1]) > 1e-6 {
+				t.Errorf("%v to %v: corner %d maps back to (%v, %v)", from, to, p, bx, by)
+			}
+		}
+	}
+}
+
+func TestSolveHomographyIdentity(t *testing.T) {
+	corners := []float64{0, 0, 4, 0, 4, 3, 0, 3}
+	h, err := solveHomography(corners, corners)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity := homography{1, 0, 0, 0, 1, 0, 0, 0, 1}
+	for i := range h {
+		if math.Abs(h[i]-identity[i]) > 1e-12 {
+			t.Fatalf("mapping corners onto themselves gave %v", h)
+		}
+	}
+}
+
+func TestHomographyErrors(t *testing.T) {
+	if _, err := solveHomography([]float64{0, 0, 1, 1, 2, 2, 0, 5}, []float64{0, 0, 1, 0, 1, 1, 0, 1}); err == nil {
+		t.Error("three points on one line should be an error")
+	}
+	if _, err := (homography{1, 2, 0, 2, 4, 0, 0, 0, 1}).invert(); err == nil {
+		t.Error("a singular matrix should not be inverted")
+	}
+	// w is negative for points behind the viewer.
+	if _, _, ok := (homography{1, 0, 0, 0, 1, 0, -1, 0, 1}).apply(2, 0); ok {
+		t.Error("a point behind the viewer should not map")
+	}
+}
+
+func TestAffineTranslate(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	img := image.NewRGBA(image.Rect(-4, 6, 12, 20))
+	rng.Read(img.Pix)
+	tfm, err := AffineT([6]float64{1, 0, 2, 0, 1, 3}, 0, 0, sampleNearest, color.NRGBA{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := tfm(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := out.(*image.RGBA)
+	if got := dst.Bounds(); got != image.Rect(0, 0, 16, 14) {
+		t.Fatalf("output covers %v, want the image size at the origin", got)
+	}
+	for y := 0; y < 14; y++ {
+		for x := 0; x < 16; x++ {
+			want := color.RGBA{}
+			if x >= 2 && y >= 3 {
+				want = img.RGBAAt(img.Rect.Min.X+x-2, img.Rect.Min.Y+y-3)
+			}
+			if got := dst.RGBAAt(x, y); got != want {
+				t.Fatalf("pixel at (%d, %d) = %v, want %v", x, y, got, want)
+			}
+		}
+	}
+
+	if _, err := AffineT([6]float64{1, 1, 0, 1, 1, 0}, 0, 0, sampleNearest, color.NRGBA{}); err == nil {
+		t.Error("a singular matrix should be an error")
+	}
+}
+
+func TestPerspectiveSize(t *testing.T) {
+	img := flatImage(30, 40, color.White)
+	tests := []struct {
+		commands string
+		want     image.Point
+	}{
+		{"perspective,src=0:0:10:0:10:20:0:20", image.Pt(10, 20)},
+		{"perspective,src=0:0:10:0:10:20:0:20,width=5", image.Pt(5, 20)},
+		{"perspective,src=0:0:10:0:10:20:0:20,height=8", image.Pt(10, 8)},
+		{"perspective,src=0:0:10:0:10:20:0:20,dst=0:0:1:0:1:1:0:1", image.Pt(30, 40)},
+		{"affine,matrix=2:0:0:0:2:0,width=7", image.Pt(7, 40)},
+	}
+	for _, tt := range tests {
+		tfms, err := TokensToTfms(SplitCommands(tt.commands), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := tfms[0](img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := out.Bounds(); got != (image.Rectangle{Max: tt.want}) {
+			t.Errorf("%s: output covers %v, want %v", tt.commands, got, tt.want)
+		}
+	}
+}
+
+func TestPerspectiveStraightensQuad(t *testing.T) {
+	// The src corners of a white square on black come out as a white
+	// image of the square's size.
+	img := squareImage(image.Pt(40, 40), image.Rect(10, 5, 30, 25))
+	tfm, err := PerspectiveT(
+		[]float64{10, 5, 30, 5, 30, 25, 10, 25},
+		[]float64{0, 0, 20, 0, 20, 20, 0, 20},
+		20, 20, sampleBilinear, color.NRGBA{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := tfm(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range out.(*image.RGBA).Pix {
+		if v != 255 {
+			t.Fatalf("byte %d of the straightened square is %d", i, v)
+		}
+	}
+}
+
+func TestProjectArgs(t *testing.T) {
+	tests := []struct {
+		commands string
+		ok       bool
+	}{
+		{"affine,matrix=1:0:0:0:1:0", true},
+		{"affine,matrix=1:0:0:0:1:0,filter=bicubic,background=#ffffff,width=3,height=4", true},
+		{"affine", false},
+		{"affine,matrix=1:0:0:0:1", false},
+		{"affine,matrix=0:0:0:0:0:0", false},
+		{"affine,matrix=1:0:0:0:1:0,filter=lanczos", false},
+		{"affine,matrix=1:0:0:0:1:0,width=-1", false},
+		{"perspective,src=0:0:9:1:8:9:1:8", true},
+		{"perspective", false},
+		{"perspective,src=0:0:0:0:0:0:0:0", false},
+		{"perspective,src=0:0:9:1:8:9:1:8,dst=0:0:1:1:2:2:3:3", false},
+	}
+	for _, tt := range tests {
+		_, err := TokensToTfms(SplitCommands(tt.commands), 1)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.commands, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: pixelate.go
+ * Description:
+ *   Pixelate or blur an image, or only some regions of it, to hide
+ *   things like license plates and faces.
+ */
+
+package main
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// The side length of the tiles that region blurs are split into.
+const BLUR_TILE_SIZE = 32
+
+/*
+ * Split a rectangle into tiles and run fn on every tile in the pool.
+ */
+func forEachTile(rect image.Rectangle, size int, fn func(tile image.Rectangle)) {
+	cols := (rect.Dx() + size - 1) / size
+	rows := (rect.Dy() + size - 1) / size
+
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(0, cols*rows, func(lo int, hi int) {
+		for i := lo; i < hi; i++ {
+			x0 := rect.Min.X + (i%cols)*size
+			y0 := rect.Min.Y + (i/cols)*size
+			fn(image.Rect(x0, y0, x0+size, y0+size).Intersect(rect))
+		}
+	})
+}
+
+/*
+ * Copy an image into a new RGBA image that transformations can draw on.
+ */
+func copyRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(bounds)
+	draw.Draw(dst, bounds, img, bounds.Min, draw.Src)
+	return dst
+}
+
+/*
+ * Get a function that will pixelate the given regions of any image, or the
+ * whole image if there are no regions. Each block of block by block pixels
+ * is filled with its average color, and blocks are spread across workers.
+ */
+func PixelateT(block int, regions []image.Rectangle) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		dst := copyRGBA(img)
+		bounds := dst.Bounds()
+
+		targets := regions
+		if len(targets) == 0 {
+			targets = []image.Rectangle{bounds}
+		}
+
+		for _, region := range targets {
+			forEachTile(region.Intersect(bounds), block, func(tile image.Rectangle) {
+				var r, g, b, a, n int
+				for y := tile.Min.Y; y < tile.Max.Y; y++ {
+					for x := tile.Min.X; x < tile.Max.X; x++ {
+						i := dst.PixOffset(x, y)
+						r += int(dst.Pix[i])
+						g += int(dst.Pix[i+1])
+						b += int(dst.Pix[i+2])
+						a += int(dst.Pix[i+3])
+						n++
+					}
+				}
+				if n == 0 {
+					return
+				}
+
+				avg := color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), uint8(a / n)}
+				draw.Draw(dst, tile, image.NewUniform(avg), image.Point{}, draw.Src)
+			})
+		}
+		return dst, nil
+	}
+}
+
+/*
+ * Get a function that will apply a Gaussian blur with the given sigma to
+ * some regions of any image. The regions are split into tiles, and the
+ * blur reads from the untouched original so tiles don't affect each other.
+ */
+func BlurRegionT(regions []image.Rectangle, sigma float64) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		kernel := gaussianKernel(sigma)
+		dst := copyRGBA(img)
+		bounds := dst.Bounds()
+
+		for _, region := range regions {
+			forEachTile(region.Intersect(bounds), BLUR_TILE_SIZE, func(tile image.Rectangle) {
+				for y := tile.Min.Y; y < tile.Max.Y; y++ {
+					for x := tile.Min.X; x < tile.Max.X; x++ {
+						r, g, b, a := convolvePixel(img, kernel, bounds, x, y)
+						dst.SetRGBA(x, y, color.RGBA{
+							uint8(math.Min(255, math.Max(0, r))),
+							uint8(math.Min(255, math.Max(0, g))),
+							uint8(math.Min(255, math.Max(0, b))),
+							uint8(math.Min(255, math.Max(0, a))),
+						})
+					}
+				}
+			})
+		}
+		return dst, nil
+	}
+}
+
+/*
+ * Build a pixelate transformation from its command line arguments.
+ */
+func pixelateFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("block", "region"); err != nil {
+		return nil, err
+	}
+
+	block, err := args.getInt("block", 16)
+	if err != nil {
+		return nil, err
+	}
+	if block < 1 {
+		return nil, errors.New("block must be at least 1")
+	}
+	regions, err := args.getRects("region")
+	if err != nil {
+		return nil, err
+	}
+	return PixelateT(block, regions), nil
+}
+
+/*
+ * Build a blurregion transformation from its command line arguments. The
+ * region is given with x, y, w and h, or with one or more region=x:y:w:h.
+ */
+func blurRegionFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("x", "y", "w", "h", "sigma", "region"); err != nil {
+		return nil, err
+	}
+
+	regions, err := args.getRects("region")
+	if err != nil {
+		return nil, err
+	}
+	if args.has("w") || args.has("h") {
+		var nums [4]int
+		for i, key := range []string{"x", "y", "w", "h"} {
+			if nums[i], err = args.getInt(key, 0); err != nil {
+				return nil, err
+			}
+		}
+		if nums[2] <= 0 || nums[3] <= 0 {
+			return nil, errors.New("w and h must be greater than 0")
+		}
+		regions = append(regions, image.Rect(nums[0], nums[1], nums[0]+nums[2], nums[1]+nums[3]))
+	}
+	if len(regions) == 0 {
+		return nil, errors.New("a region is required")
+	}
+
+	sigma, err := args.getFloat("sigma", 8)
+	if err != nil {
+		return nil, err
+	}
+	if sigma <= 0 {
+		return nil, errors.New("sigma must be greater than 0")
+	}
+	return BlurRegionT(regions, sigma), nil
+}
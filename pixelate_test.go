@@ -0,0 +1,136 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: pixelate_test.go
+ * Description:
+ *   Tests for pixelating and blurring regions of an image.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+func TestForEachTileCoversRectOnce(t *testing.T) {
+	rects := []image.Rectangle{
+		image.Rect(0, 0, 64, 64),
+		image.Rect(-5, 3, 30, 41),
+		image.Rect(2, 2, 3, 3),
+		image.Rect(0, 0, 0, 10),
+	}
+	for _, rect := range rects {
+		for _, size := range []int{1, 7, 16, 100} {
+			counts := make([]int, rect.Dx()*rect.Dy())
+			done := make(chan image.Rectangle, len(counts)+1)
+			forEachTile(rect, size, func(tile image.Rectangle) {
+				done <- tile
+			})
+			close(done)
+			for tile := range done {
+				if tile.Dx() > size || tile.Dy() > size || !tile.In(rect) {
+					t.Errorf("tile %v of %v is too big or outside", tile, rect)
+				}
+				for y := tile.Min.Y; y < tile.Max.Y; y++ {
+					for x := tile.Min.X; x < tile.Max.X; x++ {
+						counts[(y-rect.Min.Y)*rect.Dx()+x-rect.Min.X]++
+					}
+				}
+			}
+			for i, n := range counts {
+				if n != 1 {
+					t.Fatalf("%v in tiles of %d: pixel %d covered %d times", rect, size, i, n)
+				}
+			}
+		}
+	}
+}
+
+func TestPixelateAveragesBlocks(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 6, 4))
+	for x := 0; x < 6; x++ {
+		for y := 0; y < 4; y++ {
+			img.SetRGBA(x, y, color.RGBA{uint8(40 * x), uint8(10 * y), 7, 255})
+		}
+	}
+
+	out, err := PixelateT(4, nil)(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := out.(*image.RGBA)
+	tests := []struct {
+		block image.Rectangle
+		want  color.RGBA
+	}{
+		{image.Rect(0, 0, 4, 4), color.RGBA{60, 15, 7, 255}},
+		{image.Rect(4, 0, 6, 4), color.RGBA{180, 15, 7, 255}},
+	}
+	for _, tt := range tests {
+		for y := tt.block.Min.Y; y < tt.block.Max.Y; y++ {
+			for x := tt.block.Min.X; x < tt.block.Max.X; x++ {
+				if got := dst.RGBAAt(x, y); got != tt.want {
+					t.Errorf("pixel at (%d, %d) = %v, want %v", x, y, got, tt.want)
+				}
+			}
+		}
+	}
+}
+
+func TestPixelateOnlyTouchesRegions(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	rng.Read(img.Pix)
+	regions := []image.Rectangle{image.Rect(2, 2, 8, 8), image.Rect(15, 15, 40, 40)}
+
+	for name, tfm := range map[string]func(image.Image) (image.Image, error){
+		"pixelate":   PixelateT(3, regions),
+		"blurregion": BlurRegionT(regions, 2),
+	} {
+		out, err := tfm(img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst := out.(*image.RGBA)
+		changed := 0
+		for y := 0; y < 20; y++ {
+			for x := 0; x < 20; x++ {
+				p := image.Pt(x, y)
+				inside := p.In(regions[0]) || p.In(regions[1])
+				if same := dst.RGBAAt(x, y) == img.RGBAAt(x, y); !same && !inside {
+					t.Errorf("%s changed %v, outside the regions", name, p)
+				} else if !same {
+					changed++
+				}
+			}
+		}
+		if changed == 0 {
+			t.Errorf("%s did not change the regions", name)
+		}
+	}
+}
+
+func TestPixelateArgs(t *testing.T) {
+	tests := []struct {
+		commands string
+		ok       bool
+	}{
+		{"pixelate", true},
+		{"pixelate,block=4,region=0:0:10:10,region=5:5:2:2", true},
+		{"pixelate,block=0", false},
+		{"pixelate,region=1:2:3", false},
+		{"blurregion,x=1,y=2,w=3,h=4", true},
+		{"blurregion,region=0:0:5:5,sigma=2", true},
+		{"blurregion", false},
+		{"blurregion,w=0,h=4", false},
+		{"blurregion,region=0:0:5:5,sigma=0", false},
+	}
+	for _, tt := range tests {
+		_, err := TokensToTfms(SplitCommands(tt.commands), 1)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.commands, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: placement.go
+ * Description:
+ *   Find places for stickers on an image that don't overlap, with rejection
+ *   or Poisson-disk sampling, away from the subject and keep-out areas.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+)
+
+/*
+ * How stickers are placed:
+ *   method: random takes the first place that fits, and poisson spreads
+ *     stickers out evenly
+ *   attempts: how many places are tried for each sticker
+ *   inside: whether stickers must be fully inside of the image, rather than
+ *     only their anchors
+ *   margin: the smallest gap between stickers, and between stickers and the
+ *     edge when they are inside
+ *   exact: whether it is an error to place fewer stickers than asked for
+ *   avoid: whether to prefer places that the saliency map says are not
+ *     part of the subject
+ *   keepOut, keepOutMask: areas that stickers may not touch, given as
+ *     rectangles and as the bright pixels of a mask the size of the image
+ */
+type placementOptions struct {
+	method      string
+	attempts    int
+	inside      bool
+	margin      int
+	exact       bool
+	avoid       bool
+	keepOut     []image.Rectangle
+	keepOutMask image.Image
+}
+
+// How stickers are placed when nothing else is asked for.
+var DEFAULT_PLACEMENT = placementOptions{"random", 30, false, 0, false, false, nil, nil}
+
+/*
+ * Places stickers on an image one at a time, remembering where earlier
+ * ones went.
+ */
+type placer struct {
+	bounds   image.Rectangle
+	opts     placementOptions
+	rng      *rand.Rand
+	placed   []image.Rectangle
+	saliency *summedArea
+	keepOut  *summedArea
+}
+
+/*
+ * Make a placer for an image, with its saliency map and keep-out areas
+ * ready if they are used.
+ */
+func newPlacer(img image.Image, opts placementOptions, rng *rand.Rand) (*placer, error) {
+	bounds := img.Bounds()
+	p := &placer{bounds, opts, rng, make([]image.Rectangle, 0), nil, nil}
+
+	if opts.avoid {
+		saliency, err := saliencyMap(img)
+		if err != nil {
+			return nil, err
+		}
+		table := newSummedArea(bounds, saliency)
+		p.saliency = &table
+	}
+
+	if len(opts.keepOut) > 0 || opts.keepOutMask != nil {
+		mask := opts.keepOutMask
+		if mask != nil && mask.Bounds().Size() != bounds.Size() {
+			return nil, errors.New("the keep-out mask must be the same size as the image")
+		}
+		blocked := make([]float64, bounds.Dx()*bounds.Dy())
+		block := func(x int, y int) {
+			blocked[(y-bounds.Min.Y)*bounds.Dx()+x-bounds.Min.X] = 1
+		}
+		for _, r := range opts.keepOut {
+			r = r.Intersect(bounds)
+			for y := r.Min.Y; y < r.Max.Y; y++ {
+				for x := r.Min.X; x < r.Max.X; x++ {
+					block(x, y)
+				}
+			}
+		}
+		if mask != nil {
+			mb := mask.Bounds()
+			for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					mx, my := x-bounds.Min.X+mb.Min.X, y-bounds.Min.Y+mb.Min.Y
+					if color.GrayModel.Convert(mask.At(mx, my)).(color.Gray).Y >= 128 {
+						block(x, y)
+					}
+				}
+			}
+		}
+		table := newSummedArea(bounds, blocked)
+		p.keepOut = &table
+	}
+	return p, nil
+}
+
+/*
+ * Pick a random top left corner for a sticker of the given size, whose
+ * anchor is the given offset from its top left corner.
+ *
+ * Returns: The corner, and false if the sticker can't fit at all.
+ */
+func (p *placer) candidate(size image.Point, anchor image.Point) (image.Point, bool) {
+	// Stickers that may hang off the edge only need their anchor inside.
+	lo := p.bounds.Min.Sub(anchor)
+	hi := p.bounds.Max.Sub(anchor)
+	if p.opts.inside {
+		lo = p.bounds.Min.Add(image.Pt(p.opts.margin, p.opts.margin))
+		hi = p.bounds.Max.Sub(size).Sub(image.Pt(p.opts.margin, p.opts.margin)).Add(image.Pt(1, 1))
+	}
+	if hi.X <= lo.X || hi.Y <= lo.Y {
+		return image.Point{}, false
+	}
+	return image.Pt(lo.X+p.rng.Intn(hi.X-lo.X), lo.Y+p.rng.Intn(hi.Y-lo.Y)), true
+}
+
+/*
+ * Check whether an area keeps its margin from every placed sticker and
+ * stays out of the keep-out areas.
+ */
+func (p *placer) fits(area image.Rectangle) bool {
+	if p.keepOut != nil {
+		if blocked, _ := p.keepOut.sum(area); blocked > 0 {
+			return false
+		}
+	}
+	grown := area.Inset(-p.opts.margin)
+	for _, other := range p.placed {
+		if grown.Overlaps(other) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+ * Get the distance from the center of an area to the nearest center of a
+ * placed sticker.
+ */
+func (p *placer) spacing(area image.Rectangle) float64 {
+	cx := float64(area.Min.X+area.Max.X) / 2
+	cy := float64(area.Min.Y+area.Max.Y) / 2
+	nearest := math.Inf(1)
+	for _, other := range p.placed {
+		ox := float64(other.Min.X+other.Max.X) / 2
+		oy := float64(other.Min.Y+other.Max.Y) / 2
+		nearest = math.Min(nearest, math.Hypot(cx-ox, cy-oy))
+	}
+	return nearest
+}
+
+/*
+ * Get how much of an area is free of the subject, from 0 to 1.
+ */
+func (p *placer) background(area image.Rectangle) float64 {
+	sum, n := p.saliency.sum(area)
+	if n == 0 {
+		return 1
+	}
+	return 1 - sum/float64(n)
+}
+
+/*
+ * Find a place for a sticker of the given size and anchor and claim it. Up to
+ * attempts random places are tried. With random sampling the first one
+ * that fits is taken; with poisson sampling every attempt is made and the
+ * one farthest from the other stickers is taken, which spreads stickers
+ * out like Poisson-disk sampling does. When avoiding the subject, every
+ * attempt is made and places over the subject score lower.
+ *
+ * Returns: The top left corner of the sticker, and false if no place was
+ * found.
+ */
+func (p *placer) place(size image.Point, anchor image.Point) (image.Point, bool) {
+	var best image.Rectangle
+	bestScore := math.Inf(-1)
+	for i := 0; i < p.opts.attempts; i++ {
+		at, ok := p.candidate(size, anchor)
+		if !ok {
+			break
+		}
+		area := image.Rectangle{at, at.Add(size)}
+		if !p.fits(area) {
+			continue
+		}
+		if p.opts.method == "random" && p.saliency == nil {
+			best = area
+			bestScore = 0
+			break
+		}
+
+		score := 1.0
+		if p.opts.method == "poisson" && len(p.placed) > 0 {
+			score = p.spacing(area)
+		}
+		if p.saliency != nil {
+			score *= p.background(area)
+		}
+		if score > bestScore {
+			best = area
+			bestScore = score
+		}
+	}
+
+	if math.IsInf(bestScore, -1) {
+		return image.Point{}, false
+	}
+	p.placed = append(p.placed, best)
+	return best.Min, true
+}
+
+/*
+ * Read the placement options from command line arguments.
+ */
+func placementFromArgs(args tfmArgs) (placementOptions, error) {
+	opts := DEFAULT_PLACEMENT
+	opts.method = args.getString("place", opts.method)
+	if opts.method != "random" && opts.method != "poisson" {
+		return opts, fmt.Errorf("%s is not a valid place", opts.method)
+	}
+
+	var err error
+	opts.attempts, err = args.getInt("attempts", opts.attempts)
+	if err != nil {
+		return opts, err
+	}
+	opts.inside, err = args.getBool("inside", opts.inside)
+	if err != nil {
+		return opts, err
+	}
+	opts.margin, err = args.getInt("margin", opts.margin)
+	if err != nil {
+		return opts, err
+	}
+	opts.exact, err = args.getBool("exact", opts.exact)
+	if err != nil {
+		return opts, err
+	}
+	opts.avoid, err = args.getBool("avoid", opts.avoid)
+	if err != nil {
+		return opts, err
+	}
+	opts.keepOut, err = args.getRects("keepout")
+	if err != nil {
+		return opts, err
+	}
+	if args.has("keepoutmask") {
+		opts.keepOutMask, err = decodeImage(args.getString("keepoutmask", ""))
+		if err != nil {
+			return opts, err
+		}
+	}
+	if opts.attempts < 1 {
+		return opts, errors.New("attempts must be at least 1")
+	}
+	if opts.margin < 0 {
+		return opts, errors.New("margin must not be negative")
+	}
+	return opts, nil
+}
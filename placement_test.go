@@ -0,0 +1,216 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: placement_test.go
+ * Description:
+ *   Tests for finding places for stickers.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestPlacerCandidate(t *testing.T) {
+	bounds := image.Rect(10, 20, 60, 50)
+	img := image.NewRGBA(bounds)
+	size, anchor := image.Pt(8, 6), image.Pt(4, 6)
+
+	tests := []struct {
+		name   string
+		inside bool
+		margin int
+	}{
+		{"anchored", false, 0},
+		{"inside", true, 0},
+		{"inside with a margin", true, 5},
+	}
+	for _, tt := range tests {
+		opts := DEFAULT_PLACEMENT
+		opts.inside, opts.margin = tt.inside, tt.margin
+		p, err := newPlacer(img, opts, rand.New(rand.NewSource(1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 200; i++ {
+			at, ok := p.candidate(size, anchor)
+			if !ok {
+				t.Fatalf("%s: no candidate", tt.name)
+			}
+			area := image.Rectangle{at, at.Add(size)}
+			if tt.inside && !area.In(bounds.Inset(tt.margin)) {
+				t.Fatalf("%s: %v is not inside %v", tt.name, area, bounds.Inset(tt.margin))
+			}
+			if !tt.inside && !at.Add(anchor).In(bounds) {
+				t.Fatalf("%s: the anchor of %v is outside", tt.name, area)
+			}
+		}
+	}
+
+	opts := DEFAULT_PLACEMENT
+	opts.inside = true
+	p, err := newPlacer(img, opts, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.candidate(image.Pt(51, 5), image.Point{}); ok {
+		t.Error("a sticker wider than the image should not fit inside it")
+	}
+	if _, ok := p.candidate(image.Pt(50, 30), image.Point{}); !ok {
+		t.Error("a sticker the size of the image should fit inside it")
+	}
+}
+
+func TestPlacerFits(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	opts := DEFAULT_PLACEMENT
+	opts.margin = 2
+	opts.keepOut = []image.Rectangle{image.Rect(30, 30, 35, 35)}
+	p, err := newPlacer(img, opts, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.placed = append(p.placed, image.Rect(10, 10, 20, 20))
+
+	tests := []struct {
+		area image.Rectangle
+		want bool
+	}{
+		{image.Rect(0, 0, 5, 5), true},
+		{image.Rect(15, 15, 25, 25), false},
+		// Within the margin of the placed sticker.
+		{image.Rect(21, 10, 25, 20), false},
+		{image.Rect(22, 10, 25, 20), true},
+		{image.Rect(28, 28, 31, 31), false},
+		{image.Rect(35, 35, 40, 40), true},
+	}
+	for _, tt := range tests {
+		if got := p.fits(tt.area); got != tt.want {
+			t.Errorf("fits(%v) = %v, want %v", tt.area, got, tt.want)
+		}
+	}
+}
+
+func TestPlaceNeverOverlaps(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	for _, method := range []string{"random", "poisson"} {
+		opts := DEFAULT_PLACEMENT
+		opts.method, opts.inside, opts.margin = method, true, 3
+		p, err := newPlacer(img, opts, rand.New(rand.NewSource(2)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		placed := 0
+		for i := 0; i < 30; i++ {
+			if _, ok := p.place(image.Pt(12, 9), image.Pt(6, 4)); ok {
+				placed++
+			}
+		}
+		if placed < 5 {
+			t.Errorf("%s only placed %d stickers", method, placed)
+		}
+		for i, a := range p.placed {
+			if !a.In(img.Bounds().Inset(3)) {
+				t.Errorf("%s placed %v outside the margin", method, a)
+			}
+			for _, b := range p.placed[i+1:] {
+				if a.Inset(-3).Overlaps(b) {
+					t.Errorf("%s placed %v within the margin of %v", method, a, b)
+				}
+			}
+		}
+	}
+}
+
+func TestPoissonSpreadsStickers(t *testing.T) {
+	// The second sticker goes far from the first.
+	img := image.NewRGBA(image.Rect(0, 0, 200, 200))
+	opts := DEFAULT_PLACEMENT
+	opts.method, opts.inside, opts.attempts = "poisson", true, 200
+	p, err := newPlacer(img, opts, rand.New(rand.NewSource(3)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.placed = append(p.placed, image.Rect(0, 0, 10, 10))
+	at, ok := p.place(image.Pt(10, 10), image.Point{})
+	if !ok {
+		t.Fatal("the second sticker was not placed")
+	}
+	if d := math.Hypot(float64(at.X), float64(at.Y)); d < 200 {
+		t.Errorf("the second sticker is only %v away", d)
+	}
+}
+
+func TestPlacerKeepOutMask(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	mask := image.NewGray(image.Rect(0, 0, 20, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 10; x++ {
+			mask.SetGray(x, y, color.Gray{255})
+		}
+	}
+	opts := DEFAULT_PLACEMENT
+	opts.inside, opts.keepOutMask = true, mask
+	p, err := newPlacer(img, opts, rand.New(rand.NewSource(4)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		if at, ok := p.place(image.Pt(2, 2), image.Point{}); ok && at.X < 10 {
+			t.Errorf("a sticker was placed at %v, on the mask", at)
+		}
+	}
+
+	opts.keepOutMask = image.NewGray(image.Rect(0, 0, 5, 5))
+	if _, err := newPlacer(img, opts, rand.New(rand.NewSource(4))); err == nil {
+		t.Error("a mask of the wrong size should be an error")
+	}
+}
+
+func TestCatsCount(t *testing.T) {
+	// The cats are 611 and 750 pixels wide and 900 tall.
+	img := flatImage(3000, 1000, color.White)
+	tfms, err := TokensToTfms(SplitCommands("cats,count=2,inside=true,exact=true"), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tfms[0](img); err != nil {
+		t.Errorf("two cats did not fit: %v", err)
+	}
+
+	tfms, err = TokensToTfms(SplitCommands("cats,count=50,inside=true,exact=true"), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tfms[0](img); err == nil {
+		t.Error("fifty cats fit on an image with room for four")
+	}
+}
+
+func TestPlacementArgs(t *testing.T) {
+	tests := []struct {
+		commands string
+		ok       bool
+	}{
+		{"cats", true},
+		{"cats,count=3,place=poisson,attempts=5,inside=true,margin=4,exact=true", true},
+		{"cats,keepout=0:0:10:10,avoid=true", true},
+		{"cats,count=-1", false},
+		{"cats,place=grid", false},
+		{"cats,attempts=0", false},
+		{"cats,margin=-1", false},
+		{"cats,keepout=0:0", false},
+		{"cats,keepoutmask=/does/not/exist.png", false},
+		{"cats,size=3", false},
+	}
+	for _, tt := range tests {
+		_, err := TokensToTfms(SplitCommands(tt.commands), 1)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.commands, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,396 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: quantize.go
+ * Description:
+ *   Reduce an image to a small palette and turn it into a paletted image,
+ *   which is saved as a much smaller indexed PNG or GIF.
+ */
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+	"sync"
+)
+
+// How many passes k-means makes over the sample before giving up.
+const KMEANS_ITERATIONS = 16
+
+/*
+ * A node of the color octree. Each level splits on one more bit of
+ * red, green and blue.
+ */
+type octreeNode struct {
+	children [8]*octreeNode
+	leaf     bool
+	pixels   int
+	count    int
+	r        int
+	g        int
+	b        int
+}
+
+/*
+ * A color octree that gets reduced by merging its deepest nodes.
+ */
+type octree struct {
+	root      *octreeNode
+	levels    [8][]*octreeNode
+	sorted    [8]bool
+	numLeaves int
+}
+
+/*
+ * Add a color to the tree, creating nodes down to the deepest level.
+ */
+func (t *octree) insert(p [3]uint8) {
+	node := t.root
+	node.pixels++
+	for level := 0; level < 8; level++ {
+		shift := 7 - level
+		index := int(p[0]>>shift&1)<<2 | int(p[1]>>shift&1)<<1 | int(p[2]>>shift&1)
+		child := node.children[index]
+		if child == nil {
+			child = &octreeNode{leaf: level == 7}
+			node.children[index] = child
+			if child.leaf {
+				t.numLeaves++
+			} else {
+				t.levels[level] = append(t.levels[level], child)
+			}
+		}
+		node = child
+		node.pixels++
+	}
+	node.count++
+	node.r += int(p[0])
+	node.g += int(p[1])
+	node.b += int(p[2])
+}
+
+/*
+ * Merge the children of the deepest reducible node into it. Once only the
+ * leaves under the root are left, the one with the fewest pixels is merged
+ * into the one nearest to it in color instead.
+ */
+func (t *octree) reduce() {
+	level := 6
+	for level >= 0 && len(t.levels[level]) == 0 {
+		level--
+	}
+	if level < 0 {
+		t.mergeRootLeaves()
+		return
+	}
+
+	// Merge the nodes with the fewest pixels first, so that rare colors are
+	// the ones that get folded into their neighbors. Merging never changes
+	// how many pixels a node covers, so each level only needs one sort.
+	nodes := t.levels[level]
+	if !t.sorted[level] {
+		sort.Slice(nodes, func(i, j int) bool {
+			return nodes[i].pixels > nodes[j].pixels
+		})
+		t.sorted[level] = true
+	}
+	node := nodes[len(nodes)-1]
+	t.levels[level] = nodes[:len(nodes)-1]
+
+	for i, child := range node.children {
+		if child == nil {
+			continue
+		}
+		node.count += child.count
+		node.r += child.r
+		node.g += child.g
+		node.b += child.b
+		node.children[i] = nil
+		t.numLeaves--
+	}
+	node.leaf = true
+	t.numLeaves++
+}
+
+/*
+ * Merge the root's leaf with the fewest pixels into its nearest sibling.
+ */
+func (t *octree) mergeRootLeaves() {
+	children := &t.root.children
+	smallest := -1
+	for i, child := range children {
+		if child != nil && (smallest < 0 || child.pixels < children[smallest].pixels) {
+			smallest = i
+		}
+	}
+	if smallest < 0 {
+		return
+	}
+
+	from := children[smallest]
+	fr, fg, fb := from.average()
+	nearest := -1
+	bestDist := math.Inf(1)
+	for i, child := range children {
+		if child == nil || i == smallest {
+			continue
+		}
+		r, g, b := child.average()
+		if dist := colorDistSq(fr, fg, fb, r, g, b); dist < bestDist {
+			nearest = i
+			bestDist = dist
+		}
+	}
+	if nearest < 0 {
+		return
+	}
+
+	into := children[nearest]
+	into.pixels += from.pixels
+	into.count += from.count
+	into.r += from.r
+	into.g += from.g
+	into.b += from.b
+	children[smallest] = nil
+	t.numLeaves--
+}
+
+/*
+ * Get the average color of a leaf.
+ */
+func (n *octreeNode) average() (float64, float64, float64) {
+	count := float64(n.count)
+	return float64(n.r) / count, float64(n.g) / count, float64(n.b) / count
+}
+
+/*
+ * Collect the average colors of all leaves below a node.
+ */
+func (n *octreeNode) leafColors(pal color.Palette) color.Palette {
+	if n.leaf {
+		return append(pal, color.RGBA{
+			uint8(n.r / n.count),
+			uint8(n.g / n.count),
+			uint8(n.b / n.count),
+			255,
+		})
+	}
+	for _, child := range n.children {
+		if child != nil {
+			pal = child.leafColors(pal)
+		}
+	}
+	return pal
+}
+
+/*
+ * Generate a palette of at most numColors colors with an octree.
+ */
+func octreeQuantize(pixels [][3]uint8, numColors int) color.Palette {
+	if len(pixels) == 0 {
+		return color.Palette{color.RGBA{0, 0, 0, 255}}
+	}
+
+	tree := octree{root: &octreeNode{}}
+	for _, p := range pixels {
+		tree.insert(p)
+	}
+	for tree.numLeaves > numColors {
+		tree.reduce()
+	}
+	return tree.root.leafColors(nil)
+}
+
+/*
+ * Cluster pixels into k colors with k-means, starting from a median cut
+ * palette. Each pass assigns pixels to their nearest center in parallel.
+ *
+ * Returns: The cluster centers and how many pixels belong to each.
+ */
+func kmeans(pixels [][3]uint8, k int) (color.Palette, []int) {
+	start := medianCut(pixels, k)
+	centers, _ := paletteRGB(start)
+	k = len(centers)
+	counts := make([]int, k)
+	if len(pixels) == 0 {
+		return start, counts
+	}
+
+	pool := GetGlobalWorkers()
+	for iter := 0; iter < KMEANS_ITERATIONS; iter++ {
+		sums := make([][3]float64, k)
+		for i := range counts {
+			counts[i] = 0
+		}
+
+		// Every chunk of pixels sums its clusters on its own, and the
+		// partial sums are merged under a lock.
+		var mu sync.Mutex
+		pool.ParallelFor(0, len(pixels), func(lo int, hi int) {
+			localSums := make([][3]float64, k)
+			localCounts := make([]int, k)
+			for _, p := range pixels[lo:hi] {
+				r, g, b := float64(p[0]), float64(p[1]), float64(p[2])
+				c := nearestColor(centers, r, g, b)
+				localSums[c][0] += r
+				localSums[c][1] += g
+				localSums[c][2] += b
+				localCounts[c]++
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for c := 0; c < k; c++ {
+				sums[c][0] += localSums[c][0]
+				sums[c][1] += localSums[c][1]
+				sums[c][2] += localSums[c][2]
+				counts[c] += localCounts[c]
+			}
+		})
+
+		moved := 0.0
+		for c := 0; c < k; c++ {
+			if counts[c] == 0 {
+				// Keep empty clusters where they are.
+				continue
+			}
+			n := float64(counts[c])
+			next := [3]float64{sums[c][0] / n, sums[c][1] / n, sums[c][2] / n}
+			moved = math.Max(moved, colorDistSq(
+				centers[c][0], centers[c][1], centers[c][2],
+				next[0], next[1], next[2],
+			))
+			centers[c] = next
+		}
+		if moved < 1 {
+			break
+		}
+	}
+
+	pal := make(color.Palette, k)
+	for c, center := range centers {
+		pal[c] = color.RGBA{
+			uint8(math.Round(center[0])),
+			uint8(math.Round(center[1])),
+			uint8(math.Round(center[2])),
+			255,
+		}
+	}
+	return pal, counts
+}
+
+/*
+ * Check whether any pixel of an image is mostly transparent.
+ */
+func hasTransparency(img image.Image) bool {
+	bounds := img.Bounds()
+	var mu sync.Mutex
+	found := false
+
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				if _, _, _, a := img.At(x, y).RGBA(); a < 0x8000 {
+					mu.Lock()
+					found = true
+					mu.Unlock()
+					return
+				}
+			}
+		}
+	})
+	return found
+}
+
+/*
+ * Get a function that will reduce any image to a paletted image with at
+ * most numColors colors. If the image has transparent pixels, one palette
+ * entry is saved for full transparency. ditherMethod is used while mapping
+ * pixels to the palette and may be empty.
+ */
+func QuantizeT(numColors int, method string, ditherMethod string) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		transparent := hasTransparency(img)
+		opaqueColors := numColors
+		if transparent {
+			opaqueColors--
+		}
+
+		samples := samplePixels(img, MAX_PALETTE_SAMPLES)
+		var pal color.Palette
+		switch method {
+		case "median-cut":
+			pal = medianCut(samples, opaqueColors)
+		case "octree":
+			pal = octreeQuantize(samples, opaqueColors)
+		case "kmeans":
+			pal, _ = kmeans(samples, opaqueColors)
+		default:
+			return nil, fmt.Errorf("quantize: %s is not a valid method", method)
+		}
+
+		indices, err := ditherIndices(img, pal, ditherMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		bounds := img.Bounds()
+		width := bounds.Dx()
+		transparentIndex := uint8(len(pal))
+		if transparent {
+			pal = append(pal, color.RGBA{0, 0, 0, 0})
+		}
+
+		paletted := image.NewPaletted(bounds, pal)
+		pool := GetGlobalWorkers()
+		pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+			for y := lo; y < hi; y++ {
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					index := indices[(y-bounds.Min.Y)*width+x-bounds.Min.X]
+					if transparent {
+						if _, _, _, a := img.At(x, y).RGBA(); a < 0x8000 {
+							index = transparentIndex
+						}
+					}
+					paletted.SetColorIndex(x, y, index)
+				}
+			}
+		})
+		return paletted, nil
+	}
+}
+
+/*
+ * Build a quantize transformation from its command line arguments.
+ */
+func quantizeFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("colors", "method", "dither"); err != nil {
+		return nil, err
+	}
+
+	numColors, err := args.getInt("colors", 64)
+	if err != nil {
+		return nil, err
+	}
+	if numColors < 2 || numColors > MAX_PALETTE_SIZE {
+		return nil, fmt.Errorf("colors must be between 2 and %d", MAX_PALETTE_SIZE)
+	}
+
+	method := args.getString("method", "median-cut")
+	if method != "median-cut" && method != "octree" && method != "kmeans" {
+		return nil, fmt.Errorf("%s is not a valid method", method)
+	}
+
+	ditherMethod := args.getString("dither", "none")
+	_, isOrdered := BAYER_SIZES[ditherMethod]
+	_, isDiffusion := DIFFUSION_KERNELS[ditherMethod]
+	if ditherMethod != "none" && !isOrdered && !isDiffusion {
+		return nil, fmt.Errorf("%s is not a valid dithering method", ditherMethod)
+	}
+
+	return QuantizeT(numColors, method, ditherMethod), nil
+}
@@ -0,0 +1,123 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: quantize_test.go
+ * Description:
+ *   Tests for octree and k-means quantization and paletted output.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"sort"
+	"testing"
+)
+
+func TestOctreeQuantizeColorCount(t *testing.T) {
+	samples := samplePixels(gradientImage(64, 64), MAX_PALETTE_SAMPLES)
+	// Merging a node can take away several leaves at once, so there may
+	// be fewer colors than asked for, but never more.
+	for _, n := range []int{2, 3, 4, 7, 8, 16, 64} {
+		if pal := octreeQuantize(samples, n); len(pal) < 1 || len(pal) > n {
+			t.Errorf("octreeQuantize(%d) made %d colors", n, len(pal))
+		}
+	}
+	// The last few leaves are merged one at a time.
+	if pal := octreeQuantize(samples, 2); len(pal) != 2 {
+		t.Errorf("octreeQuantize(2) made %d colors, want 2", len(pal))
+	}
+}
+
+func TestOctreeQuantizeFewColors(t *testing.T) {
+	samples := [][3]uint8{{255, 0, 0}, {255, 0, 0}, {0, 0, 255}}
+	pal := octreeQuantize(samples, 8)
+	if len(pal) != 2 {
+		t.Fatalf("octreeQuantize of 2 colors made %d colors, want 2", len(pal))
+	}
+	if pal := octreeQuantize(samples, 1); len(pal) != 1 {
+		t.Errorf("octreeQuantize(1) made %d colors, want 1", len(pal))
+	}
+	if pal := octreeQuantize(nil, 4); len(pal) != 1 {
+		t.Errorf("octreeQuantize of no pixels made %d colors, want 1", len(pal))
+	}
+}
+
+func TestKmeansClusters(t *testing.T) {
+	pixels := make([][3]uint8, 0)
+	for i := 0; i < 30; i++ {
+		pixels = append(pixels, [3]uint8{0, 0, uint8(i % 3)}, [3]uint8{250, 250, uint8(250 - i%3)})
+	}
+	pal, counts := kmeans(pixels, 2)
+	if len(pal) != 2 {
+		t.Fatalf("kmeans made %d centers, want 2", len(pal))
+	}
+	got := []color.RGBA{pal[0].(color.RGBA), pal[1].(color.RGBA)}
+	sort.Slice(got, func(i, j int) bool { return got[i].R < got[j].R })
+	want := []color.RGBA{{0, 0, 1, 255}, {250, 250, 249, 255}}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("kmeans center %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if counts[0]+counts[1] != len(pixels) || counts[0] != counts[1] {
+		t.Errorf("kmeans counts = %v, want 30 and 30", counts)
+	}
+}
+
+func TestQuantizeT(t *testing.T) {
+	opaque := gradientImage(40, 30)
+	clear := gradientImage(40, 30)
+	clear.SetRGBA(5, 5, color.RGBA{})
+
+	tests := []struct {
+		img         image.Image
+		method      string
+		numColors   int
+		transparent bool
+	}{
+		{opaque, "median-cut", 8, false},
+		{opaque, "octree", 4, false},
+		{opaque, "kmeans", 5, false},
+		{clear, "median-cut", 8, true},
+		{clear, "octree", 2, true},
+	}
+	for _, tt := range tests {
+		out, err := QuantizeT(tt.numColors, tt.method, "none")(tt.img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paletted, ok := out.(*image.Paletted)
+		if !ok {
+			t.Fatalf("%s made a %T, want *image.Paletted", tt.method, out)
+		}
+		if len(paletted.Palette) > tt.numColors {
+			t.Errorf("%s with %d colors made %d colors", tt.method, tt.numColors, len(paletted.Palette))
+		}
+		_, _, _, a := paletted.At(5, 5).RGBA()
+		if (a == 0) != tt.transparent {
+			t.Errorf("%s: alpha at the cleared pixel = %d, want transparent %v", tt.method, a, tt.transparent)
+		}
+	}
+}
+
+func TestQuantizeFromArgs(t *testing.T) {
+	tests := []struct {
+		commands string
+		ok       bool
+	}{
+		{"quantize", true},
+		{"quantize,colors=2,method=octree", true},
+		{"quantize,colors=256,method=kmeans,dither=floyd-steinberg", true},
+		{"quantize,colors=1", false},
+		{"quantize,colors=257", false},
+		{"quantize,method=popularity", false},
+		{"quantize,dither=random", false},
+	}
+	for _, tt := range tests {
+		_, err := TokensToTfms(SplitCommands(tt.commands), 1)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.commands, err, tt.ok)
+		}
+	}
+}
@@ -84,6 +84,13 @@ func pixelBilinear(
     scaledX := float64(newX) * (1.0 / factor)
     scaledY := float64(newY) * (1.0 / factor)
 
+    newImg.Set(newX, newY, sampleBilinear(ogImg, scaledX, scaledY))
+}
+
+/*
+ * Get the color at a point between pixels using bilinear interpolation.
+ */
+func sampleBilinear(ogImg image.Image, scaledX float64, scaledY float64) color.RGBA {
     x0 := math.Floor(scaledX)
     x1 := x0 + 1
     y0 := math.Floor(scaledY)
@@ -98,12 +105,7 @@ func pixelBilinear(
     // that pixel's color.
     if x0 == x1 && y0 == y1 {
 	    r, g, b, a := ogImg.At(x0Int, y0Int).RGBA()
-	    newImg.Set(
-		newX,
-	        newY,
-		color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)},
-	    )
-	    return
+	    return color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
     }
 
     // Get the colors of the four neighboring pixels.
@@ -125,7 +127,73 @@ func pixelBilinear(
     // Do a vertical linear interpolation with the two previous results.
     clr := topLinClr.scalarMult(topYWeight).add(botLinClr.scalarMult(botYWeight))
 
-    newImg.Set(newX, newY, clr.toRGBA())
+    return clr.toRGBA()
+}
+
+/*
+ * A way to get the color at a point between pixels.
+ */
+type resampleFilter func(ogImg image.Image, scaledX float64, scaledY float64) color.RGBA
+
+/*
+ * The resampling filters that can be chosen by name.
+ */
+var RESAMPLE_FILTERS = map[string]resampleFilter{
+    "nearest":  sampleNearest,
+    "bilinear": sampleBilinear,
+    "bicubic":  sampleBicubic,
+}
+
+/*
+ * Get the color of the pixel closest to a point.
+ */
+func sampleNearest(ogImg image.Image, scaledX float64, scaledY float64) color.RGBA {
+    r, g, b, a := ogImg.At(int(math.Round(scaledX)), int(math.Round(scaledY))).RGBA()
+    return color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+}
+
+/*
+ * Get the weight of a neighbor at distance t for Catmull-Rom interpolation.
+ */
+func cubicWeight(t float64) float64 {
+    t = math.Abs(t)
+    if t <= 1 {
+	return 1.5*t*t*t - 2.5*t*t + 1
+    }
+    if t < 2 {
+	return -0.5*t*t*t + 2.5*t*t - 4*t + 2
+    }
+    return 0
+}
+
+/*
+ * Get the color at a point between pixels using bicubic (Catmull-Rom)
+ * interpolation over the 16 nearest pixels. Neighbors past the edge are
+ * clamped to it.
+ */
+func sampleBicubic(ogImg image.Image, scaledX float64, scaledY float64) color.RGBA {
+    bounds := ogImg.Bounds()
+    x0 := math.Floor(scaledX)
+    y0 := math.Floor(scaledY)
+
+    var clr vector4
+    for j := -1; j <= 2; j++ {
+	yWeight := cubicWeight(scaledY - (y0 + float64(j)))
+	y := clamp(int(y0)+j, bounds.Min.Y, bounds.Max.Y-1)
+	for i := -1; i <= 2; i++ {
+	    xWeight := cubicWeight(scaledX - (x0 + float64(i)))
+	    x := clamp(int(x0)+i, bounds.Min.X, bounds.Max.X-1)
+	    clr = clr.add(colorToVector4(ogImg.At(x, y)).scalarMult(xWeight * yWeight))
+	}
+    }
+
+    // The weights can overshoot, so keep the color valid. The color is
+    // premultiplied, so no channel can be larger than alpha.
+    clr.A = math.Min(255, math.Max(0, math.Round(clr.A)))
+    clr.X = math.Min(clr.A, math.Max(0, math.Round(clr.X)))
+    clr.Y = math.Min(clr.A, math.Max(0, math.Round(clr.Y)))
+    clr.Z = math.Min(clr.A, math.Max(0, math.Round(clr.Z)))
+    return clr.toRGBA()
 }
 
 /*
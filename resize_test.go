@@ -0,0 +1,85 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: resize_test.go
+ * Description:
+ *   Tests for the resampling filters.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+func TestResampleFilters(t *testing.T) {
+	// Gray levels, premultiplied, in a 2x2 image.
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	for i, v := range []uint8{0, 100, 200, 100} {
+		img.SetRGBA(i%2, i/2, color.RGBA{v, v, v, 255})
+	}
+
+	tests := []struct {
+		filter string
+		x, y   float64
+		want   uint8
+	}{
+		{"nearest", 0, 0, 0},
+		{"nearest", 0.4, 0.6, 200},
+		{"nearest", 0.6, 0.4, 100},
+		{"bilinear", 0, 0, 0},
+		{"bilinear", 1, 1, 100},
+		{"bilinear", 0.5, 0, 50},
+		{"bilinear", 0, 0.5, 100},
+		{"bilinear", 0.5, 0.5, 100},
+		{"bicubic", 0, 0, 0},
+		{"bicubic", 1, 0, 100},
+		{"bicubic", 0, 1, 200},
+	}
+	for _, tt := range tests {
+		got := RESAMPLE_FILTERS[tt.filter](img, tt.x, tt.y)
+		if got != (color.RGBA{tt.want, tt.want, tt.want, 255}) {
+			t.Errorf("%s at (%v, %v) = %v, want gray %d", tt.filter, tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestCubicWeight(t *testing.T) {
+	tests := []struct {
+		t, want float64
+	}{
+		{0, 1},
+		{1, 0},
+		{-1, 0},
+		{2, 0},
+		{0.5, 0.5625},
+		{-1.5, -0.0625},
+		{3, 0},
+	}
+	for _, tt := range tests {
+		if got := cubicWeight(tt.t); got != tt.want {
+			t.Errorf("cubicWeight(%v) = %v, want %v", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestBicubicStaysPremultiplied(t *testing.T) {
+	// Hard edges make Catmull-Rom overshoot.
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if rng.Intn(2) == 0 {
+				img.SetRGBA(x, y, color.RGBA{255, 0, 255, 255})
+			}
+		}
+	}
+	for i := 0; i < 500; i++ {
+		c := sampleBicubic(img, rng.Float64()*7, rng.Float64()*7)
+		if c.R > c.A || c.G > c.A || c.B > c.A {
+			t.Fatalf("bicubic made %v, which has more color than alpha", c)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: saliency.go
+ * Description:
+ *   A cheap saliency map that guesses where the subject of an image is,
+ *   from how busy and how unusual in color every area is.
+ */
+
+package main
+
+import (
+	"image"
+	"math"
+)
+
+/*
+ * Scale values in place so that the largest one is 1.
+ */
+func normalizeValues(values []float64) {
+	var largest float64
+	for _, v := range values {
+		largest = math.Max(largest, v)
+	}
+	if largest == 0 {
+		return
+	}
+	for i := range values {
+		values[i] /= largest
+	}
+}
+
+/*
+ * Compute how likely every pixel is to be part of the subject, from 0 to
+ * 1. It is the average of two cues: edge density, the Sobel gradient
+ * averaged over a small window, and color contrast, how far the lightly
+ * blurred color is from the average color of the whole image.
+ *
+ * Returns: The saliency of every pixel, row by row.
+ */
+func saliencyMap(img image.Image) ([]float64, error) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	field, err := computeGradients(img, "sobel")
+	if err != nil {
+		return nil, err
+	}
+	gradients := newSummedArea(bounds, field.mag)
+	radius := max(2, min(width, height)/32)
+
+	blurred := convolveParallel(img, gaussianKernel(2))
+	var mean [3]float64
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			i := blurred.PixOffset(x, y)
+			for c := 0; c < 3; c++ {
+				mean[c] += float64(blurred.Pix[i+c])
+			}
+		}
+	}
+	for c := range mean {
+		mean[c] /= float64(width * height)
+	}
+
+	edges := make([]float64, width*height)
+	contrast := make([]float64, width*height)
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(0, height, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := 0; x < width; x++ {
+				px, py := bounds.Min.X+x, bounds.Min.Y+y
+				window := image.Rect(px-radius, py-radius, px+radius+1, py+radius+1)
+				sum, n := gradients.sum(window)
+				edges[y*width+x] = sum / float64(n)
+
+				i := blurred.PixOffset(px, py)
+				contrast[y*width+x] = math.Sqrt(colorDistSq(
+					float64(blurred.Pix[i]), float64(blurred.Pix[i+1]), float64(blurred.Pix[i+2]),
+					mean[0], mean[1], mean[2],
+				))
+			}
+		}
+	})
+
+	normalizeValues(edges)
+	normalizeValues(contrast)
+	saliency := make([]float64, width*height)
+	for i := range saliency {
+		saliency[i] = (edges[i] + contrast[i]) / 2
+	}
+	return saliency, nil
+}
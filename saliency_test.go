@@ -0,0 +1,113 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: saliency_test.go
+ * Description:
+ *   Tests for the saliency map and placing stickers away from the subject.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeValues(t *testing.T) {
+	tests := []struct {
+		values []float64
+		want   []float64
+	}{
+		{[]float64{1, 2, 4}, []float64{0.25, 0.5, 1}},
+		{[]float64{0, 0}, []float64{0, 0}},
+		{[]float64{3}, []float64{1}},
+		{[]float64{}, []float64{}},
+	}
+	for _, tt := range tests {
+		values := append([]float64{}, tt.values...)
+		normalizeValues(values)
+		if !reflect.DeepEqual(values, tt.want) {
+			t.Errorf("normalizeValues(%v) = %v, want %v", tt.values, values, tt.want)
+		}
+	}
+}
+
+/*
+ * Make a gray image with a busy, colorful square on it.
+ */
+func subjectImage(size image.Point, subject image.Rectangle) *image.RGBA {
+	rng := rand.New(rand.NewSource(1))
+	img := flatImage(size.X, size.Y, color.Gray{120})
+	for y := subject.Min.Y; y < subject.Max.Y; y++ {
+		for x := subject.Min.X; x < subject.Max.X; x++ {
+			img.SetRGBA(x, y, color.RGBA{uint8(200 + rng.Intn(56)), uint8(rng.Intn(60)), 40, 255})
+		}
+	}
+	return img
+}
+
+func TestSaliencyMap(t *testing.T) {
+	flat, err := saliencyMap(flatImage(20, 10, color.White))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range flat {
+		if v != 0 {
+			t.Fatalf("a flat image has saliency %v at %d", v, i)
+		}
+	}
+
+	size := image.Pt(64, 48)
+	subject := image.Rect(24, 16, 40, 32)
+	img := subjectImage(size, subject)
+	// The map works the same wherever the image starts.
+	moved := image.NewRGBA(img.Bounds().Add(image.Pt(-7, 11)))
+	draw.Draw(moved, moved.Bounds(), img, image.Point{}, draw.Src)
+
+	for _, src := range []image.Image{img, moved} {
+		saliency, err := saliencyMap(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(saliency) != size.X*size.Y {
+			t.Fatalf("map has %d values, want %d", len(saliency), size.X*size.Y)
+		}
+		largest := 0.0
+		for _, v := range saliency {
+			if v < 0 || v > 1 {
+				t.Fatalf("saliency %v is outside 0 to 1", v)
+			}
+			largest = max(largest, v)
+		}
+		if largest < 0.5 {
+			t.Errorf("the most salient pixel only has %v", largest)
+		}
+		center, corner := saliency[32*size.X+32], saliency[2*size.X+2]
+		if center <= 0.3 || corner >= 0.1 {
+			t.Errorf("the subject has %v and the corner %v", center, corner)
+		}
+	}
+}
+
+func TestPlacementAvoidsSubject(t *testing.T) {
+	// The subject fills the left half.
+	img := subjectImage(image.Pt(80, 40), image.Rect(0, 0, 40, 40))
+	opts := DEFAULT_PLACEMENT
+	opts.inside, opts.avoid = true, true
+	p, err := newPlacer(img, opts, rand.New(rand.NewSource(2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		at, ok := p.place(image.Pt(8, 8), image.Point{})
+		if !ok {
+			t.Fatal("no place was found")
+		}
+		if at.X < 40 {
+			t.Errorf("sticker %d was placed at %v, on the subject", i, at)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: shadow.go
+ * Description:
+ *   Rounded corners, drop shadows and glows, for cutting images out as
+ *   cards and lifting them off the page.
+ */
+
+package main
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+/*
+ * Get a function that will round the corners of any image, leaving them
+ * transparent with anti-aliased edges.
+ */
+func RoundCornersT(radius float64) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		newImg := copyRGBA(img)
+		bounds := newImg.Bounds()
+
+		pool := GetGlobalWorkers()
+		pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+			for y := lo; y < hi; y++ {
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					coverage := roundedRectCoverage(float64(x)+0.5, float64(y)+0.5, bounds, radius)
+					if coverage == 1 {
+						continue
+					}
+					// The pixels are premultiplied, so every channel fades.
+					i := newImg.PixOffset(x, y)
+					for c := 0; c < 4; c++ {
+						newImg.Pix[i+c] = uint8(math.Round(float64(newImg.Pix[i+c]) * coverage))
+					}
+				}
+			}
+		})
+		return newImg, nil
+	}
+}
+
+/*
+ * Blur an alpha mask with a Gaussian of the given sigma, across and then
+ * down, which gives the same result as convolving with gaussianKernel for a
+ * fraction of the work. Everything outside of the mask counts as
+ * transparent.
+ */
+func blurAlpha(mask *image.Alpha, sigma float64) *image.Alpha {
+	bounds := mask.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	radius := gaussianRadius(sigma)
+	weights := gaussianWeights(sigma, radius)
+
+	across := make([]float64, width*height)
+	blurred := image.NewAlpha(bounds)
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(0, height, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			row := mask.Pix[y*mask.Stride : y*mask.Stride+width]
+			for x := 0; x < width; x++ {
+				var v float64
+				for k := max(0, x-radius); k <= min(width-1, x+radius); k++ {
+					v += float64(row[k]) * weights[k-x+radius]
+				}
+				across[y*width+x] = v
+			}
+		}
+	})
+	pool.ParallelFor(0, height, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := 0; x < width; x++ {
+				var v float64
+				for k := max(0, y-radius); k <= min(height-1, y+radius); k++ {
+					v += across[k*width+x] * weights[k-y+radius]
+				}
+				blurred.Pix[y*blurred.Stride+x] = uint8(math.Round(math.Min(255, v)))
+			}
+		}
+	})
+	return blurred
+}
+
+/*
+ * Get a function that will put any image on a canvas with a blurred copy
+ * of its silhouette behind it, moved by offset. The canvas grows to fit
+ * the shadow. With no offset, the shadow is a glow all around the image.
+ */
+func ShadowT(offset image.Point, sigma float64, clr color.NRGBA, opacity float64) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		src := toRGBA(img)
+		bounds := src.Bounds()
+		// The canvas has room for as far as the blur reaches.
+		reach := 0
+		if sigma > 0 {
+			reach = gaussianRadius(sigma)
+		}
+		canvas := bounds.Union(bounds.Add(offset)).Inset(-reach)
+
+		// The silhouette is the alpha of the image, where the shadow falls.
+		silhouette := image.NewAlpha(canvas)
+		pool := GetGlobalWorkers()
+		pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+			for y := lo; y < hi; y++ {
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					silhouette.Pix[silhouette.PixOffset(x+offset.X, y+offset.Y)] = src.Pix[src.PixOffset(x, y)+3]
+				}
+			}
+		})
+		shadow := silhouette
+		if sigma > 0 {
+			shadow = blurAlpha(silhouette, sigma)
+		}
+
+		shade := clr
+		shade.A = uint8(math.Round(float64(clr.A) * opacity))
+		dst := image.NewRGBA(canvas.Sub(canvas.Min))
+		pool.ParallelFor(canvas.Min.Y, canvas.Max.Y, func(lo int, hi int) {
+			rows := image.Rect(canvas.Min.X, lo, canvas.Max.X, hi)
+			draw.DrawMask(dst, rows.Sub(canvas.Min), image.NewUniform(shade), image.Point{}, shadow, rows.Min, draw.Src)
+			if over := bounds.Intersect(rows); !over.Empty() {
+				draw.Draw(dst, over.Sub(canvas.Min), src, over.Min, draw.Over)
+			}
+		})
+		return dst, nil
+	}
+}
+
+/*
+ * Build a roundcorners transformation from its command line arguments.
+ */
+func roundCornersFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("radius"); err != nil {
+		return nil, err
+	}
+
+	radius, err := args.getFloat("radius", 16)
+	if err != nil {
+		return nil, err
+	}
+	if radius < 0 {
+		return nil, errors.New("radius must not be negative")
+	}
+	return RoundCornersT(radius), nil
+}
+
+/*
+ * Read the blur, color and opacity shared by shadow and glow.
+ */
+func shadowArgs(args tfmArgs, sigma float64, clr string, opacity float64) (float64, color.NRGBA, float64, error) {
+	sigma, err := args.getFloat("blur", sigma)
+	if err != nil {
+		return 0, color.NRGBA{}, 0, err
+	}
+	shade, err := parseColor(args.getString("color", clr))
+	if err != nil {
+		return 0, color.NRGBA{}, 0, err
+	}
+	opacity, err = args.getFloat("opacity", opacity)
+	if err != nil {
+		return 0, color.NRGBA{}, 0, err
+	}
+	if sigma < 0 {
+		return 0, color.NRGBA{}, 0, errors.New("blur must not be negative")
+	}
+	if opacity < 0 || opacity > 1 {
+		return 0, color.NRGBA{}, 0, errors.New("opacity must be between 0 and 1")
+	}
+	return sigma, shade, opacity, nil
+}
+
+/*
+ * Build a shadow transformation from its command line arguments.
+ */
+func shadowFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("offset", "blur", "color", "opacity"); err != nil {
+		return nil, err
+	}
+
+	offset := image.Pt(8, 8)
+	if args.has("offset") {
+		xy, err := args.getFloats("offset", 2)
+		if err != nil {
+			return nil, err
+		}
+		offset = image.Pt(int(math.Round(xy[0])), int(math.Round(xy[1])))
+	}
+	sigma, shade, opacity, err := shadowArgs(args, 8, "black", 0.5)
+	if err != nil {
+		return nil, err
+	}
+	return ShadowT(offset, sigma, shade, opacity), nil
+}
+
+/*
+ * Build a glow transformation from its command line arguments.
+ */
+func glowFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("blur", "color", "opacity"); err != nil {
+		return nil, err
+	}
+
+	sigma, shade, opacity, err := shadowArgs(args, 10, "white", 0.8)
+	if err != nil {
+		return nil, err
+	}
+	return ShadowT(image.Point{}, sigma, shade, opacity), nil
+}
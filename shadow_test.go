@@ -0,0 +1,212 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: shadow_test.go
+ * Description:
+ *   Tests for rounded corners, drop shadows and glows.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestRoundCorners(t *testing.T) {
+	// The corners come out the same wherever the image starts.
+	img := image.NewRGBA(image.Rect(-7, 3, 13, 23))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{200, 100, 50, 255}), image.Point{}, draw.Src)
+	out, err := RoundCornersT(5)(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rgba := out.(*image.RGBA)
+	if rgba.Bounds() != img.Bounds() {
+		t.Fatalf("image covers %v, want %v", rgba.Bounds(), img.Bounds())
+	}
+
+	tests := []struct {
+		at   image.Point
+		want uint8
+	}{
+		{image.Pt(-7, 3), 0},
+		{image.Pt(12, 3), 0},
+		{image.Pt(-7, 22), 0},
+		{image.Pt(12, 22), 0},
+		{image.Pt(3, 3), 255},
+		{image.Pt(-7, 13), 255},
+		{image.Pt(-5, 5), 255},
+	}
+	for _, tt := range tests {
+		if got := rgba.RGBAAt(tt.at.X, tt.at.Y).A; got != tt.want {
+			t.Errorf("%v has alpha %d, want %d", tt.at, got, tt.want)
+		}
+	}
+
+	// Edge pixels are partly covered, and stay premultiplied.
+	partial := 0
+	for i := 0; i < len(rgba.Pix); i += 4 {
+		a := rgba.Pix[i+3]
+		if a > 0 && a < 255 {
+			partial++
+		}
+		if rgba.Pix[i] > a || rgba.Pix[i+1] > a || rgba.Pix[i+2] > a {
+			t.Fatalf("pixel %d is %v, brighter than its alpha", i/4, rgba.Pix[i:i+4])
+		}
+	}
+	if partial == 0 {
+		t.Error("the corners are not anti-aliased")
+	}
+
+	out, err = RoundCornersT(0)(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out.(*image.RGBA).Pix) != string(img.Pix) {
+		t.Error("a radius of 0 changed the image")
+	}
+}
+
+func TestBlurAlpha(t *testing.T) {
+	mask := image.NewAlpha(image.Rect(0, 0, 21, 21))
+	mask.SetAlpha(10, 10, color.Alpha{255})
+	blurred := blurAlpha(mask, 2)
+
+	sum := 0
+	for y := 0; y < 21; y++ {
+		for x := 0; x < 21; x++ {
+			v := blurred.AlphaAt(x, y).A
+			sum += int(v)
+			if v != blurred.AlphaAt(20-x, y).A || v != blurred.AlphaAt(y, x).A {
+				t.Fatalf("the blur is not symmetric at (%d, %d)", x, y)
+			}
+		}
+	}
+	// Rounding each pixel loses or gains at most half a level.
+	if sum < 255-21*21/2 || sum > 255+21*21/2 {
+		t.Errorf("the blurred mask adds up to %d, want about 255", sum)
+	}
+	if peak := blurred.AlphaAt(10, 10).A; peak == 0 || peak >= 255 {
+		t.Errorf("the center is %d, want it spread out", peak)
+	}
+}
+
+func TestShadowCanvasSize(t *testing.T) {
+	tests := []struct {
+		offset image.Point
+		sigma  float64
+		size   image.Point
+	}{
+		{image.Pt(8, 8), 2, image.Pt(10+8+2*6, 10+8+2*6)},
+		{image.Pt(-3, 0), 0, image.Pt(13, 10)},
+		{image.Pt(0, 0), 1, image.Pt(10+2*3, 10+2*3)},
+		{image.Pt(0, 0), 0.1, image.Pt(12, 12)},
+		{image.Pt(0, 0), 0, image.Pt(10, 10)},
+	}
+	img := image.NewRGBA(image.Rect(5, 5, 15, 15))
+	for _, tt := range tests {
+		out, err := ShadowT(tt.offset, tt.sigma, color.NRGBA{0, 0, 0, 255}, 1)(img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := out.Bounds(); got != (image.Rectangle{Max: tt.size}) {
+			t.Errorf("offset %v and blur %v: canvas is %v, want %v", tt.offset, tt.sigma, got, tt.size)
+		}
+	}
+}
+
+func TestShadowPlacement(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	black := color.RGBA{0, 0, 0, 255}
+	out, err := ShadowT(image.Pt(2, 2), 0, color.NRGBA{0, 0, 0, 255}, 1)(flatImage(4, 4, red))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rgba := out.(*image.RGBA)
+	tests := []struct {
+		at   image.Point
+		want color.RGBA
+	}{
+		{image.Pt(0, 0), red},
+		{image.Pt(3, 3), red},
+		{image.Pt(5, 5), black},
+		{image.Pt(4, 2), black},
+		{image.Pt(5, 0), color.RGBA{}},
+		{image.Pt(0, 5), color.RGBA{}},
+	}
+	for _, tt := range tests {
+		if got := rgba.RGBAAt(tt.at.X, tt.at.Y); got != tt.want {
+			t.Errorf("%v is %v, want %v", tt.at, got, tt.want)
+		}
+	}
+
+	// Half opacity halves the shadow's alpha.
+	out, err = ShadowT(image.Pt(2, 2), 0, color.NRGBA{0, 0, 0, 255}, 0.5)(flatImage(4, 4, red))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out.(*image.RGBA).RGBAAt(5, 5).A; got != 128 {
+		t.Errorf("the shadow has alpha %d, want 128", got)
+	}
+}
+
+func TestGlow(t *testing.T) {
+	tfms, err := TokensToTfms(SplitCommands("glow,blur=2,color=white,opacity=1"), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := tfms[0](flatImage(6, 6, color.Black))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rgba := out.(*image.RGBA)
+	// The image sits in the middle, with the same glow on every side.
+	reach := gaussianRadius(2)
+	size := 6 + 2*reach
+	if rgba.Bounds() != image.Rect(0, 0, size, size) {
+		t.Fatalf("canvas is %v, want %dx%d", rgba.Bounds(), size, size)
+	}
+	if got := rgba.RGBAAt(reach, reach); got != (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("the image's corner is %v, want black", got)
+	}
+	if rgba.RGBAAt(reach-1, size/2).A == 0 {
+		t.Error("there is no glow beside the image")
+	}
+	for i := 0; i < reach; i++ {
+		left := rgba.RGBAAt(i, size/2)
+		for _, p := range []image.Point{{size - 1 - i, size / 2}, {size / 2, i}, {size / 2, size - 1 - i}} {
+			if got := rgba.RGBAAt(p.X, p.Y); got != left {
+				t.Errorf("the glow is %v at %v and %v on the left", got, p, left)
+			}
+		}
+	}
+}
+
+func TestShadowArgs(t *testing.T) {
+	tests := []struct {
+		commands string
+		ok       bool
+	}{
+		{"roundcorners", true},
+		{"roundcorners,radius=0", true},
+		{"roundcorners,radius=-1", false},
+		{"roundcorners,size=3", false},
+		{"shadow", true},
+		{"shadow,offset=-4:6,blur=0,color=#336699,opacity=1", true},
+		{"shadow,offset=4", false},
+		{"shadow,blur=-1", false},
+		{"shadow,color=nope", false},
+		{"shadow,opacity=1.5", false},
+		{"glow", true},
+		{"glow,blur=4,color=#ffd700,opacity=0.5", true},
+		{"glow,offset=1:1", false},
+	}
+	for _, tt := range tests {
+		_, err := TokensToTfms(SplitCommands(tt.commands), 1)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.commands, err, tt.ok)
+		}
+	}
+}
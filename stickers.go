@@ -0,0 +1,242 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: stickers.go
+ * Description:
+ *   Scatter stickers over an image. Every sticker is picked from a pack of
+ *   PNG, JPEG or GIF images and gets its own scale, rotation, opacity and
+ *   flip.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"math/rand"
+	"sync"
+)
+
+/*
+ * How stickers are scattered. Every sticker gets a random scale between
+ * minScale and maxScale, or from the range its pack gives it if minScale
+ * is 0, a random rotation of up to maxRotation degrees either way if its
+ * pack lets it turn, a random opacity between minOpacity and 1, and, if
+ * flip is set, a coin toss for being mirrored. A count of 0 picks a random
+ * count from the range the pack gives.
+ */
+type stickerOptions struct {
+	count       int
+	minScale    float64
+	maxScale    float64
+	maxRotation float64
+	minOpacity  float64
+	flip        bool
+	placement   placementOptions
+}
+
+/*
+ * Draw a sticker scaled by scale, turned by angle degrees and mirrored if
+ * flip is set, on a canvas just big enough to hold it.
+ *
+ * Returns: The drawn sticker, and where its anchor, given as fractions of
+ * its width and height, ended up relative to its top left corner.
+ */
+func transformSticker(img image.Image, scale float64, angle float64, flip bool, anchor [2]float64) (*image.RGBA, image.Point) {
+	bounds := img.Bounds()
+	ax := float64(bounds.Dx()) * anchor[0]
+	ay := float64(bounds.Dy()) * anchor[1]
+	if scale == 1 && angle == 0 && !flip {
+		// Copied to the top left, where the turned stickers are drawn too.
+		out := image.NewRGBA(image.Rectangle{Max: bounds.Size()})
+		draw.Draw(out, out.Bounds(), img, bounds.Min, draw.Src)
+		return out, image.Pt(int(math.Round(ax)), int(math.Round(ay)))
+	}
+
+	w, h := float64(bounds.Dx())*scale, float64(bounds.Dy())*scale
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	// Right angles leave rounding errors that would round up a whole pixel.
+	outW := math.Ceil(math.Abs(w*cos) + math.Abs(h*sin) - 1e-9)
+	outH := math.Ceil(math.Abs(w*sin) + math.Abs(h*cos) - 1e-9)
+	cx, cy := centerOf(bounds)
+
+	// Turn, scale and mirror every output point back onto the sticker.
+	mapping := func(x float64, y float64) (float64, float64, bool) {
+		dx, dy := x-outW/2, y-outH/2
+		sx := (dx*cos + dy*sin) / scale
+		sy := (-dx*sin + dy*cos) / scale
+		if flip {
+			sx = -sx
+		}
+		return cx + sx, cy + sy, true
+	}
+	out := image.Rect(0, 0, max(1, int(outW)), max(1, int(outH)))
+
+	// The anchor goes the other way, from the sticker onto the output.
+	u, v := float64(bounds.Min.X)+ax-cx, float64(bounds.Min.Y)+ay-cy
+	if flip {
+		u = -u
+	}
+	at := image.Pt(
+		int(math.Round(outW/2+(u*cos-v*sin)*scale)),
+		int(math.Round(outH/2+(u*sin+v*cos)*scale)),
+	)
+	return warpImage(img, out, mapping, sampleBilinear, color.NRGBA{}), at
+}
+
+/*
+ * Get a random number between lo and hi.
+ */
+func randomBetween(rng *rand.Rand, lo float64, hi float64) float64 {
+	return lo + rng.Float64()*(hi-lo)
+}
+
+/*
+ * Get a function that will scatter stickers from a pack over any image
+ * without overlapping them. A sticker that can't be placed is left out,
+ * or is an error if the placement is exact. The same seed always makes
+ * the same choices.
+ */
+func StickersT(pack *stickerPack, opts stickerOptions, seed int64) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		rng := rand.New(rand.NewSource(seed))
+		newImg := copyRGBA(img)
+		placer, err := newPlacer(img, opts.placement, rng)
+		if err != nil {
+			return nil, err
+		}
+
+		count := opts.count
+		if count == 0 {
+			count = pack.minCount + rng.Intn(pack.maxCount-pack.minCount+1)
+		}
+
+		type placement struct {
+			img     *image.RGBA
+			at      image.Point
+			opacity float64
+		}
+		placements := make([]placement, 0, count)
+		for i := 0; i < count; i++ {
+			picked := pack.pick(rng)
+			minScale, maxScale := opts.minScale, opts.maxScale
+			if minScale == 0 {
+				minScale, maxScale = picked.minScale, picked.maxScale
+			}
+			scale := randomBetween(rng, minScale, maxScale)
+			angle := randomBetween(rng, -opts.maxRotation, opts.maxRotation)
+			if !picked.rotatable {
+				angle = 0
+			}
+			sticker, anchor := transformSticker(picked.img, scale, angle, opts.flip && rng.Intn(2) == 1, picked.anchor)
+
+			at, ok := placer.place(sticker.Bounds().Size(), anchor)
+			if !ok {
+				continue
+			}
+			placements = append(placements, placement{sticker, at, randomBetween(rng, opts.minOpacity, 1)})
+		}
+
+		if opts.placement.exact && len(placements) < count {
+			return nil, fmt.Errorf("only found room for %d of %d stickers", len(placements), count)
+		}
+
+		// Placed stickers don't overlap, so they can be drawn at the same time.
+		pool := GetGlobalWorkers()
+		var wg sync.WaitGroup
+		wg.Add(len(placements))
+		for _, p := range placements {
+			func(p placement) {
+				pool.Submit(func() {
+					defer wg.Done()
+					opacity := image.NewUniform(color.Alpha{uint8(math.Round(p.opacity * 255))})
+					draw.DrawMask(
+						newImg,
+						p.img.Bounds().Add(p.at),
+						p.img,
+						p.img.Bounds().Min,
+						opacity,
+						image.Point{},
+						draw.Over,
+					)
+				})
+			}(p)
+		}
+		wg.Wait()
+
+		return newImg, nil
+	}
+}
+
+/*
+ * Build a stickers transformation from its command line arguments. The
+ * seed is used unless another one is given.
+ */
+func stickersFromArgs(args tfmArgs, seed int64) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow(
+		"pack", "dir", "count", "min", "max", "rotate", "opacity", "flip", "seed",
+		"place", "attempts", "inside", "margin", "exact", "avoid", "keepout", "keepoutmask",
+	); err != nil {
+		return nil, err
+	}
+
+	count, err := args.getInt("count", 0)
+	if err != nil {
+		return nil, err
+	}
+	minScale, err := args.getFloat("min", 1)
+	if err != nil {
+		return nil, err
+	}
+	maxScale, err := args.getFloat("max", max(1, minScale))
+	if err != nil {
+		return nil, err
+	}
+	rotation, err := args.getFloat("rotate", 0)
+	if err != nil {
+		return nil, err
+	}
+	opacity, err := args.getFloat("opacity", 1)
+	if err != nil {
+		return nil, err
+	}
+	flip, err := args.getBool("flip", false)
+	if err != nil {
+		return nil, err
+	}
+	seed, err = args.getSeed(seed)
+	if err != nil {
+		return nil, err
+	}
+	placement, err := placementFromArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	if count < 0 {
+		return nil, errors.New("count must not be negative")
+	}
+	if minScale <= 0 || maxScale < minScale {
+		return nil, errors.New("min must be greater than 0 and no more than max")
+	}
+	if !args.has("min") && !args.has("max") {
+		// Use the scale range that the pack gives every sticker.
+		minScale, maxScale = 0, 0
+	}
+	if opacity < 0 || opacity > 1 {
+		return nil, errors.New("opacity must be between 0 and 1")
+	}
+
+	if args.has("pack") && args.has("dir") {
+		return nil, errors.New("only one of pack and dir may be given")
+	}
+
+	pack, err := loadStickerPack(args.getString("pack", DEFAULT_PACK), args.getString("dir", ""))
+	if err != nil {
+		return nil, err
+	}
+	opts := stickerOptions{count, minScale, maxScale, math.Abs(rotation), opacity, flip, placement}
+	return StickersT(pack, opts, seed), nil
+}
@@ -0,0 +1,155 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: stickers_test.go
+ * Description:
+ *   Tests for transforming and scattering stickers.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+/*
+ * Make a sticker pack with one opaque square sticker of the given color.
+ */
+func squarePack(size int, clr color.Color) *stickerPack {
+	return &stickerPack{
+		[]packSticker{{flatImage(size, size, clr), 1, 1, 1, [2]float64{0.5, 0.5}, true}},
+		1, 1,
+	}
+}
+
+func TestTransformStickerIdentity(t *testing.T) {
+	// A sticker that doesn't start at the origin still comes out at it.
+	img := image.NewRGBA(image.Rect(7, -3, 11, 3))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i)
+	}
+	out, at := transformSticker(img, 1, 0, false, [2]float64{0.5, 1})
+	if got := out.Bounds(); got != image.Rect(0, 0, 4, 6) {
+		t.Fatalf("sticker covers %v, want a zero origin", got)
+	}
+	if string(out.Pix) != string(img.Pix) {
+		t.Error("the sticker's pixels changed")
+	}
+	if at != image.Pt(2, 6) {
+		t.Errorf("anchor is at %v, want (2, 6)", at)
+	}
+}
+
+func TestTransformSticker(t *testing.T) {
+	// A 4x2 sticker with a red top left corner.
+	img := flatImage(4, 2, color.White)
+	img.SetRGBA(0, 0, color.RGBA{255, 0, 0, 255})
+	red := func(c color.RGBA) bool { return c.R > 200 && c.G < 100 }
+
+	tests := []struct {
+		name     string
+		scale    float64
+		angle    float64
+		flip     bool
+		anchor   [2]float64
+		size     image.Point
+		wantAt   image.Point
+		redPixel image.Point
+	}{
+		{"scaled", 2, 0, false, [2]float64{0, 0}, image.Pt(8, 4), image.Pt(0, 0), image.Pt(0, 0)},
+		{"turned", 1, 90, false, [2]float64{0, 0}, image.Pt(2, 4), image.Pt(2, 0), image.Pt(1, 0)},
+		{"flipped", 1, 0, true, [2]float64{0, 0.5}, image.Pt(4, 2), image.Pt(4, 1), image.Pt(3, 0)},
+		{"upside down", 1, 180, false, [2]float64{1, 1}, image.Pt(4, 2), image.Pt(0, 0), image.Pt(3, 1)},
+	}
+	for _, tt := range tests {
+		out, at := transformSticker(img, tt.scale, tt.angle, tt.flip, tt.anchor)
+		if got := out.Bounds(); got != (image.Rectangle{Max: tt.size}) {
+			t.Errorf("%s: sticker covers %v, want %v", tt.name, got, tt.size)
+			continue
+		}
+		if at != tt.wantAt {
+			t.Errorf("%s: anchor is at %v, want %v", tt.name, at, tt.wantAt)
+		}
+		if !red(out.RGBAAt(tt.redPixel.X, tt.redPixel.Y)) {
+			t.Errorf("%s: the red corner is not at %v", tt.name, tt.redPixel)
+		}
+	}
+}
+
+func TestStickersAreSeeded(t *testing.T) {
+	img := flatImage(60, 60, color.Black)
+	opts := stickerOptions{4, 1, 1, 0, 1, false, DEFAULT_PLACEMENT}
+	opts.placement.inside = true
+	pack := squarePack(5, color.White)
+
+	run := func(seed int64) *image.RGBA {
+		out, err := StickersT(pack, opts, seed)(img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out.(*image.RGBA)
+	}
+	first := run(7)
+	if string(first.Pix) != string(run(7).Pix) {
+		t.Error("the same seed placed the stickers differently")
+	}
+	if string(first.Pix) == string(run(8).Pix) {
+		t.Error("different seeds placed the stickers the same")
+	}
+
+	// Four stickers that don't overlap cover four times their area.
+	white := 0
+	for i := 0; i < len(first.Pix); i += 4 {
+		if first.Pix[i] == 255 {
+			white++
+		}
+	}
+	if white != 4*25 {
+		t.Errorf("stickers cover %d pixels, want %d", white, 4*25)
+	}
+}
+
+func TestStickersOpacity(t *testing.T) {
+	img := flatImage(10, 10, color.Black)
+	opts := stickerOptions{1, 1, 1, 0, 0.5, false, DEFAULT_PLACEMENT}
+	opts.placement.inside = true
+	out, err := StickersT(squarePack(10, color.White), opts, 1)(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every pixel gets the same opacity, between 0.5 and 1.
+	v := out.(*image.RGBA).RGBAAt(0, 0).R
+	if v < 127 || v == 0 {
+		t.Errorf("sticker drawn at %d, want at least half opacity", v)
+	}
+	for _, p := range out.(*image.RGBA).Pix {
+		if p != v && p != 255 {
+			t.Fatalf("sticker has uneven opacity: %d and %d", v, p)
+		}
+	}
+}
+
+func TestStickersArgs(t *testing.T) {
+	tests := []struct {
+		commands string
+		ok       bool
+	}{
+		{"stickers", true},
+		{"stickers,count=3,min=0.5,max=2,rotate=30,opacity=0.5,flip=true", true},
+		{"stickers,count=-1", false},
+		{"stickers,min=0", false},
+		{"stickers,min=2,max=1", false},
+		{"stickers,opacity=1.5", false},
+		{"stickers,pack=cats,dir=.", false},
+		{"stickers,pack=dogs", false},
+		{"stickers,pack=../cats", false},
+		{"stickers,size=3", false},
+	}
+	for _, tt := range tests {
+		_, err := TokensToTfms(SplitCommands(tt.commands), 1)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.commands, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: text.go
+ * Description:
+ *   Stamp text such as captions and copyright lines on an image, in a
+ *   built-in bitmap font, wrapped to a width and aligned, with an optional
+ *   stroke and shadow.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+)
+
+// How many samples across and down each pixel is split into to find how
+// much of it a glyph covers.
+const TEXT_SAMPLES = 4
+
+/*
+ * How text is drawn:
+ *   font: the font the text is drawn in
+ *   size: the height of a capital letter in pixels
+ *   color: the color of the letters
+ *   gravity, margin: where the text goes, and how far it stays from the
+ *     edges in pixels
+ *   width: the widest a line may be in pixels before it is wrapped, or 0
+ *     for the width of the image inside of the margins
+ *   align: how lines line up with each other, left, center or right
+ *   stroke, strokeColor: how thick the outline around the letters is in
+ *     pixels, and its color
+ *   shadow, shadowColor: how far the shadow is offset in pixels, and its
+ *     color
+ */
+type textOptions struct {
+	font        *bitmapFont
+	size        float64
+	color       color.NRGBA
+	gravity     string
+	margin      int
+	width       int
+	align       string
+	stroke      float64
+	strokeColor color.NRGBA
+	shadow      image.Point
+	shadowColor color.NRGBA
+}
+
+// How text is drawn when nothing else is asked for.
+var DEFAULT_TEXT = textOptions{
+	&FONT_5X7, 24, color.NRGBA{255, 255, 255, 255}, "southeast", 16, 0, "left",
+	0, color.NRGBA{0, 0, 0, 255}, image.Point{}, color.NRGBA{0, 0, 0, 128},
+}
+
+/*
+ * Break text into lines of at most maxChars characters, between words where
+ * possible. Line breaks that are already in the text are kept.
+ */
+func wrapText(text string, maxChars int) []string {
+	lines := make([]string, 0)
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := make([]rune, 0, maxChars)
+		for _, word := range strings.Fields(paragraph) {
+			runes := []rune(word)
+			if len(line) > 0 && len(line)+1+len(runes) > maxChars {
+				lines = append(lines, string(line))
+				line = line[:0]
+			}
+			// Words too long for a line of their own are split.
+			for len(runes) > maxChars {
+				lines = append(lines, string(runes[:maxChars]))
+				runes = runes[maxChars:]
+			}
+			if len(line) > 0 {
+				line = append(line, ' ')
+			}
+			line = append(line, runes...)
+		}
+		lines = append(lines, string(line))
+	}
+	return lines
+}
+
+/*
+ * Draw lines of text as an alpha mask, with pad pixels of space around it.
+ * Every glyph dot is a square unit pixels across, and the alpha of each
+ * pixel is how much of it the dots cover.
+ */
+func textMask(lines [][]rune, font *bitmapFont, unit float64, align string, pad int) *image.Alpha {
+	advance := float64(font.width+1) * unit
+	lineHeight := float64(font.height+2) * unit
+
+	longest := 0
+	for _, line := range lines {
+		longest = max(longest, len(line))
+	}
+	blockWidth := math.Max(0, float64(longest)*advance-unit)
+	blockHeight := float64(len(lines))*lineHeight - 2*unit
+
+	starts := make([]float64, len(lines))
+	for i, line := range lines {
+		free := blockWidth - math.Max(0, float64(len(line))*advance-unit)
+		switch align {
+		case "center":
+			starts[i] = free / 2
+		case "right":
+			starts[i] = free
+		}
+	}
+
+	size := image.Pt(int(math.Ceil(blockWidth))+2*pad, int(math.Ceil(blockHeight))+2*pad)
+	mask := image.NewAlpha(image.Rectangle{Max: size})
+
+	// Whether a point, relative to the top left of the text, is on a dot.
+	covered := func(x float64, y float64) bool {
+		i := int(y / lineHeight)
+		if y < 0 || i >= len(lines) {
+			return false
+		}
+		row := int((y - float64(i)*lineHeight) / unit)
+		x -= starts[i]
+		if x < 0 || row >= font.height {
+			return false
+		}
+		units := int(x / unit)
+		char, col := units/(font.width+1), units%(font.width+1)
+		if char >= len(lines[i]) || col >= font.width {
+			return false
+		}
+		return font.dot(lines[i][char], col, row)
+	}
+
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(0, size.Y, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := 0; x < size.X; x++ {
+				hits := 0
+				for sy := 0; sy < TEXT_SAMPLES; sy++ {
+					for sx := 0; sx < TEXT_SAMPLES; sx++ {
+						px := float64(x-pad) + (float64(sx)+0.5)/TEXT_SAMPLES
+						py := float64(y-pad) + (float64(sy)+0.5)/TEXT_SAMPLES
+						if covered(px, py) {
+							hits++
+						}
+					}
+				}
+				mask.Pix[mask.PixOffset(x, y)] = uint8(hits * 255 / (TEXT_SAMPLES * TEXT_SAMPLES))
+			}
+		}
+	})
+	return mask
+}
+
+/*
+ * Grow an alpha mask by radius pixels in every direction, which gives the
+ * outline of text drawn with it. Pixels at the edge of the disc count by
+ * how far inside of it they are, to keep the outline smooth.
+ */
+func dilateAlpha(mask *image.Alpha, radius float64) *image.Alpha {
+	bounds := mask.Bounds()
+	grown := image.NewAlpha(bounds)
+	reach := int(math.Ceil(radius))
+
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				var strongest float64
+				for dy := -reach; dy <= reach; dy++ {
+					for dx := -reach; dx <= reach; dx++ {
+						weight := math.Min(1, radius+0.5-math.Hypot(float64(dx), float64(dy)))
+						if weight <= 0 || !image.Pt(x+dx, y+dy).In(bounds) {
+							continue
+						}
+						value := float64(mask.AlphaAt(x+dx, y+dy).A) * weight
+						strongest = math.Max(strongest, value)
+					}
+				}
+				grown.SetAlpha(x, y, color.Alpha{uint8(math.Round(strongest))})
+			}
+		}
+	})
+	return grown
+}
+
+/*
+ * Get a function that will stamp text on any image.
+ */
+func TextT(text string, opts textOptions) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		bounds := img.Bounds()
+		newImg := copyRGBA(img)
+		inner := bounds.Inset(opts.margin)
+		if inner.Empty() {
+			return nil, errors.New("the margin leaves no room for the text")
+		}
+
+		unit := opts.size / float64(opts.font.height)
+		width := float64(inner.Dx())
+		if opts.width > 0 {
+			width = float64(opts.width)
+		}
+		maxChars := max(1, int((width+unit)/(float64(opts.font.width+1)*unit)))
+		lines := make([][]rune, 0)
+		for _, line := range wrapText(text, maxChars) {
+			lines = append(lines, []rune(line))
+		}
+
+		pad := int(math.Ceil(opts.stroke))
+		fill := textMask(lines, opts.font, unit, opts.align, pad)
+		outline := fill
+		if opts.stroke > 0 {
+			outline = dilateAlpha(fill, opts.stroke)
+		}
+
+		textSize := fill.Bounds().Size().Sub(image.Pt(2*pad, 2*pad))
+		offset, err := gravityOffset(opts.gravity, inner.Size(), textSize)
+		if err != nil {
+			return nil, err
+		}
+		at := inner.Min.Add(offset).Sub(image.Pt(pad, pad))
+
+		// The shadow, outline and letters are drawn in that order, each by
+		// every worker over its own rows of the image.
+		type layer struct {
+			mask *image.Alpha
+			at   image.Point
+			clr  color.NRGBA
+		}
+		layers := make([]layer, 0, 3)
+		if opts.shadow != (image.Point{}) {
+			layers = append(layers, layer{outline, at.Add(opts.shadow), opts.shadowColor})
+		}
+		if opts.stroke > 0 {
+			layers = append(layers, layer{outline, at, opts.strokeColor})
+		}
+		layers = append(layers, layer{fill, at, opts.color})
+
+		pool := GetGlobalWorkers()
+		pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+			rows := image.Rect(bounds.Min.X, lo, bounds.Max.X, hi)
+			for _, l := range layers {
+				area := l.mask.Bounds().Add(l.at).Intersect(rows)
+				if area.Empty() {
+					continue
+				}
+				draw.DrawMask(newImg, area, image.NewUniform(l.clr), image.Point{}, l.mask, area.Min.Sub(l.at), draw.Over)
+			}
+		})
+
+		return newImg, nil
+	}
+}
+
+/*
+ * Build a text transformation from its command line arguments. A \n in the
+ * text starts a new line, and commas are written as \, like in any value.
+ */
+func textFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow(
+		"text", "font", "size", "color", "gravity", "margin", "width", "align",
+		"stroke", "strokecolor", "shadow", "shadowcolor",
+	); err != nil {
+		return nil, err
+	}
+	if !args.has("text") {
+		return nil, errors.New("the text must be given")
+	}
+	text := strings.ReplaceAll(args.getString("text", ""), `\n`, "\n")
+
+	opts := DEFAULT_TEXT
+	var err error
+	fontName := args.getString("font", "5x7")
+	opts.font = FONTS[fontName]
+	if opts.font == nil {
+		return nil, fmt.Errorf("%s is not a valid font", fontName)
+	}
+	opts.size, err = args.getFloat("size", opts.size)
+	if err != nil {
+		return nil, err
+	}
+	opts.color, err = parseColor(args.getString("color", "white"))
+	if err != nil {
+		return nil, err
+	}
+	opts.gravity = args.getString("gravity", opts.gravity)
+	if _, prs := GRAVITIES[opts.gravity]; !prs {
+		return nil, fmt.Errorf("%s is not a valid gravity", opts.gravity)
+	}
+	opts.margin, err = args.getInt("margin", opts.margin)
+	if err != nil {
+		return nil, err
+	}
+	opts.width, err = args.getInt("width", opts.width)
+	if err != nil {
+		return nil, err
+	}
+	opts.align = args.getString("align", opts.align)
+	if opts.align != "left" && opts.align != "center" && opts.align != "right" {
+		return nil, fmt.Errorf("%s is not a valid align", opts.align)
+	}
+	opts.stroke, err = args.getFloat("stroke", opts.stroke)
+	if err != nil {
+		return nil, err
+	}
+	opts.strokeColor, err = parseColor(args.getString("strokecolor", "black"))
+	if err != nil {
+		return nil, err
+	}
+	if args.has("shadow") {
+		offset, err := args.getFloats("shadow", 2)
+		if err != nil {
+			return nil, err
+		}
+		opts.shadow = image.Pt(int(math.Round(offset[0])), int(math.Round(offset[1])))
+	}
+	opts.shadowColor, err = parseColor(args.getString("shadowcolor", "#00000080"))
+	if err != nil {
+		return nil, err
+	}
+	if opts.size <= 0 {
+		return nil, errors.New("size must be greater than 0")
+	}
+	if opts.margin < 0 || opts.width < 0 || opts.stroke < 0 {
+		return nil, errors.New("margin, width and stroke must not be negative")
+	}
+	return TextT(text, opts), nil
+}
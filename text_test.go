@@ -0,0 +1,210 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: text_test.go
+ * Description:
+ *   Tests for wrapping and stamping text.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		text     string
+		maxChars int
+		want     []string
+	}{
+		{"hello world", 5, []string{"hello", "world"}},
+		{"hello world", 11, []string{"hello world"}},
+		{"a b c", 3, []string{"a b", "c"}},
+		{"  spaced   out  ", 20, []string{"spaced out"}},
+		{"abcdefg", 3, []string{"abc", "def", "g"}},
+		{"hi abcdefg", 3, []string{"hi", "abc", "def", "g"}},
+		{"one\ntwo", 10, []string{"one", "two"}},
+		{"ab\n\ncd", 10, []string{"ab", "", "cd"}},
+		{"©©©©", 2, []string{"©©", "©©"}},
+		{"", 4, []string{""}},
+	}
+	for _, tt := range tests {
+		if got := wrapText(tt.text, tt.maxChars); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("wrapText(%q, %d) = %q, want %q", tt.text, tt.maxChars, got, tt.want)
+		}
+	}
+}
+
+func TestTextMask(t *testing.T) {
+	font := &FONT_5X7
+	mask := textMask([][]rune{[]rune("I")}, font, 1, "left", 2)
+	if got := mask.Bounds(); got != image.Rect(0, 0, 9, 11) {
+		t.Fatalf("mask covers %v, want a glyph and its padding", got)
+	}
+	// With one pixel to a dot, the mask is the glyph itself.
+	for y := 0; y < 11; y++ {
+		for x := 0; x < 9; x++ {
+			want := uint8(0)
+			if x >= 2 && x < 7 && y >= 2 && y < 9 && font.dot('I', x-2, y-2) {
+				want = 255
+			}
+			if got := mask.AlphaAt(x, y).A; got != want {
+				t.Fatalf("(%d, %d) is %d, want %d", x, y, got, want)
+			}
+		}
+	}
+
+	// A short line over a long one is moved by how much shorter it is.
+	lines := [][]rune{[]rune("I"), []rune("III")}
+	tests := []struct {
+		align string
+		start int
+	}{
+		{"left", 0},
+		{"center", 6},
+		{"right", 12},
+	}
+	for _, tt := range tests {
+		mask := textMask(lines, font, 1, tt.align, 0)
+		if got := mask.Bounds().Size(); got != image.Pt(17, 16) {
+			t.Fatalf("%s: mask is %v, want 17x16", tt.align, got)
+		}
+		for x := 0; x < 17; x++ {
+			want := uint8(0)
+			if x-tt.start >= 0 && x-tt.start < 5 && font.dot('I', x-tt.start, 0) {
+				want = 255
+			}
+			if got := mask.AlphaAt(x, 0).A; got != want {
+				t.Errorf("%s: (%d, 0) is %d, want %d", tt.align, x, got, want)
+			}
+		}
+	}
+
+	// Half a pixel to a dot gives partly covered pixels.
+	half := textMask([][]rune{[]rune("-")}, font, 0.5, "left", 0)
+	if got := half.AlphaAt(0, 1).A; got != 127 {
+		t.Errorf("a half covered pixel is %d, want 127", got)
+	}
+}
+
+func TestDilateAlpha(t *testing.T) {
+	mask := image.NewAlpha(image.Rect(0, 0, 11, 11))
+	mask.SetAlpha(5, 5, color.Alpha{255})
+	grown := dilateAlpha(mask, 2)
+
+	tests := []struct {
+		at   image.Point
+		want uint8
+	}{
+		{image.Pt(5, 5), 255},
+		{image.Pt(6, 5), 255},
+		{image.Pt(6, 6), 255},
+		// Half of a pixel two away is inside the disc.
+		{image.Pt(7, 5), 128},
+		{image.Pt(7, 7), 0},
+		{image.Pt(8, 5), 0},
+	}
+	for _, tt := range tests {
+		if got := grown.AlphaAt(tt.at.X, tt.at.Y).A; got != tt.want {
+			t.Errorf("%v is %d, want %d", tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestTextDraw(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	opts := DEFAULT_TEXT
+	opts.size, opts.gravity, opts.margin = 7, "northwest", 1
+	opts.shadow, opts.shadowColor = image.Pt(1, 1), red
+
+	out, err := TextT("L", opts)(flatImage(10, 10, color.Black))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rgba := out.(*image.RGBA)
+	tests := []struct {
+		at   image.Point
+		want color.RGBA
+	}{
+		// The stem and foot of the L, its shadow, and the background.
+		{image.Pt(1, 1), color.RGBA{255, 255, 255, 255}},
+		{image.Pt(5, 7), color.RGBA{255, 255, 255, 255}},
+		{image.Pt(2, 2), color.RGBA{255, 0, 0, 255}},
+		{image.Pt(6, 8), color.RGBA{255, 0, 0, 255}},
+		{image.Pt(3, 3), color.RGBA{0, 0, 0, 255}},
+		{image.Pt(0, 0), color.RGBA{0, 0, 0, 255}},
+	}
+	for _, tt := range tests {
+		if got := rgba.RGBAAt(tt.at.X, tt.at.Y); got != tt.want {
+			t.Errorf("%v is %v, want %v", tt.at, got, tt.want)
+		}
+	}
+
+	opts.shadow, opts.stroke, opts.strokeColor = image.Point{}, 2, red
+	out, err = TextT("L", opts)(flatImage(10, 10, color.Black))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out.(*image.RGBA).RGBAAt(2, 1); got != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("beside the stem is %v, want the stroke", got)
+	}
+	if got := out.(*image.RGBA).RGBAAt(1, 1); got != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("the stroke covered the letter with %v", got)
+	}
+}
+
+func TestTextWraps(t *testing.T) {
+	// Eleven pixels fit two characters at one pixel to a dot.
+	opts := DEFAULT_TEXT
+	opts.size, opts.gravity, opts.margin, opts.width = 7, "northwest", 0, 11
+	img := flatImage(20, 20, color.Black)
+
+	wrapped, err := TextT("LL LL", opts)(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	broken, err := TextT("LL\nLL", opts)(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(wrapped.(*image.RGBA).Pix) != string(broken.(*image.RGBA).Pix) {
+		t.Error("the text was not wrapped to the width")
+	}
+
+	opts.margin = 10
+	if _, err := TextT("L", opts)(img); err == nil {
+		t.Error("a margin that covers the image should be an error")
+	}
+}
+
+func TestTextArgs(t *testing.T) {
+	tests := []struct {
+		commands string
+		ok       bool
+	}{
+		{"text,text=hello", true},
+		{`text,text=© ACME\, 2026\nAll rights reserved,size=12,color=#ff0000,gravity=south`, true},
+		{"text,text=hi,font=5x7,margin=0,width=100,align=center", true},
+		{"text,text=hi,stroke=2,strokecolor=white,shadow=2:3,shadowcolor=#00000040", true},
+		{"text", false},
+		{"text,text=hi,font=helvetica", false},
+		{"text,text=hi,size=0", false},
+		{"text,text=hi,color=nope", false},
+		{"text,text=hi,gravity=up", false},
+		{"text,text=hi,align=justify", false},
+		{"text,text=hi,margin=-1", false},
+		{"text,text=hi,stroke=-1", false},
+		{"text,text=hi,shadow=2", false},
+		{"text,text=hi,size=3:4", false},
+		{"text,text=hi,opacity=1", false},
+	}
+	for _, tt := range tests {
+		_, err := TokensToTfms(SplitCommands(tt.commands), 1)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.commands, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: threshold.go
+ * Description:
+ *   Turn images into black and white with a fixed, Otsu or adaptive
+ *   threshold.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// The palette of 1-bit threshold output.
+var BINARY_PALETTE = color.Palette{color.Gray{0}, color.Gray{255}}
+
+/*
+ * Find the threshold that best separates a histogram into two classes
+ * by maximizing the variance between them (Otsu's method).
+ */
+func otsuThreshold(hist [256]int) uint8 {
+	total := 0
+	var sum float64
+	for v, n := range hist {
+		total += n
+		sum += float64(v * n)
+	}
+
+	var best uint8
+	var bestVariance, sumBack float64
+	countBack := 0
+	for t := 0; t < 256; t++ {
+		countBack += hist[t]
+		if countBack == 0 {
+			continue
+		}
+		countFore := total - countBack
+		if countFore == 0 {
+			break
+		}
+		sumBack += float64(t * hist[t])
+
+		meanBack := sumBack / float64(countBack)
+		meanFore := (sum - sumBack) / float64(countFore)
+		variance := float64(countBack) * float64(countFore) * (meanBack - meanFore) * (meanBack - meanFore)
+		if variance > bestVariance {
+			best, bestVariance = uint8(t), variance
+		}
+	}
+	return best
+}
+
+/*
+ * Build a black and white image where a pixel is white when keep says so.
+ * The image is either grayscale or 1-bit paletted.
+ */
+func binaryImage(bounds image.Rectangle, paletted bool, keep func(x int, y int) bool) image.Image {
+	var pix []uint8
+	var offset func(x int, y int) int
+	var out image.Image
+	white := uint8(255)
+
+	if paletted {
+		img := image.NewPaletted(bounds, BINARY_PALETTE)
+		pix, offset, out, white = img.Pix, img.PixOffset, img, 1
+	} else {
+		img := image.NewGray(bounds)
+		pix, offset, out = img.Pix, img.PixOffset, img
+	}
+
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				if keep(x, y) {
+					pix[offset(x, y)] = white
+				}
+			}
+		}
+	})
+	return out
+}
+
+/*
+ * Get a function that will threshold any image. With otsu set, the
+ * threshold is picked from the image's histogram and value is ignored.
+ */
+func ThresholdT(value uint8, otsu bool, paletted bool) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		gray := toGray(img)
+		threshold := value
+		if otsu {
+			threshold = otsuThreshold(grayHistogram(gray))
+		}
+
+		return binaryImage(gray.Bounds(), paletted, func(x int, y int) bool {
+			return gray.GrayAt(x, y).Y > threshold
+		}), nil
+	}
+}
+
+/*
+ * A summed-area table, which gives the sum of any rectangle of values in
+ * constant time.
+ */
+type summedArea struct {
+	bounds image.Rectangle
+	sums   []float64
+}
+
+/*
+ * Build a summed-area table over values given row by row for bounds.
+ */
+func newSummedArea(bounds image.Rectangle, values []float64) summedArea {
+	width, height := bounds.Dx(), bounds.Dy()
+	stride := width + 1
+	// sums[(y+1)*stride+(x+1)] holds the sum of all values above and to the
+	// left of (x, y), inclusive.
+	sums := make([]float64, stride*(height+1))
+
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(0, height, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			var run float64
+			for x := 0; x < width; x++ {
+				run += values[y*width+x]
+				sums[(y+1)*stride+x+1] = run
+			}
+		}
+	})
+	pool.ParallelFor(1, stride, func(lo int, hi int) {
+		for y := 1; y <= height; y++ {
+			for x := lo; x < hi; x++ {
+				sums[y*stride+x] += sums[(y-1)*stride+x]
+			}
+		}
+	})
+	return summedArea{bounds, sums}
+}
+
+/*
+ * Get the sum and the number of values in the part of a rectangle that is
+ * inside the table.
+ */
+func (s summedArea) sum(r image.Rectangle) (float64, int) {
+	r = r.Intersect(s.bounds)
+	if r.Empty() {
+		return 0, 0
+	}
+	stride := s.bounds.Dx() + 1
+	x0, y0 := r.Min.X-s.bounds.Min.X, r.Min.Y-s.bounds.Min.Y
+	x1, y1 := r.Max.X-s.bounds.Min.X, r.Max.Y-s.bounds.Min.Y
+	sum := s.sums[y1*stride+x1] - s.sums[y0*stride+x1] - s.sums[y1*stride+x0] + s.sums[y0*stride+x0]
+	return sum, r.Dx() * r.Dy()
+}
+
+/*
+ * Compute the mean brightness around every pixel of a grayscale image,
+ * over a block by block window, with a summed-area table.
+ */
+func localMeans(gray *image.Gray, block int) []float64 {
+	bounds := gray.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	values := make([]float64, width*height)
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(0, height, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := 0; x < width; x++ {
+				values[y*width+x] = float64(gray.GrayAt(bounds.Min.X+x, bounds.Min.Y+y).Y)
+			}
+		}
+	})
+	table := newSummedArea(bounds, values)
+
+	// Windows are cut off at the edges, so they average fewer pixels there.
+	radius := block / 2
+	means := make([]float64, width*height)
+	pool.ParallelFor(0, height, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := 0; x < width; x++ {
+				px, py := bounds.Min.X+x, bounds.Min.Y+y
+				sum, n := table.sum(image.Rect(px-radius, py-radius, px+radius+1, py+radius+1))
+				means[y*width+x] = sum / float64(n)
+			}
+		}
+	})
+	return means
+}
+
+/*
+ * Compute the Gaussian weighted mean brightness around every pixel of a
+ * grayscale image over a block by block window.
+ */
+func localGaussianMeans(gray *image.Gray, block int) []float64 {
+	// The same sigma OpenCV picks for a given block size.
+	sigma := 0.3*(float64(block-1)*0.5-1) + 0.8
+	return gaussianBlurGray(gray, sigma, block/2)
+}
+
+/*
+ * Blur a grayscale image with a Gaussian of the given sigma that is cut
+ * off after radius pixels, with one horizontal and one vertical pass.
+ *
+ * Returns: The blurred brightness of every pixel, row by row.
+ */
+func gaussianBlurGray(gray *image.Gray, sigma float64, radius int) []float64 {
+	bounds := gray.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	weights := gaussianWeights(sigma, radius)
+
+	rows := make([]float64, width*height)
+	blurred := make([]float64, width*height)
+
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(0, height, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := 0; x < width; x++ {
+				var sum float64
+				for i, w := range weights {
+					px := clamp(x+i-radius, 0, width-1)
+					sum += float64(gray.GrayAt(bounds.Min.X+px, bounds.Min.Y+y).Y) * w
+				}
+				rows[y*width+x] = sum
+			}
+		}
+	})
+	pool.ParallelFor(0, height, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := 0; x < width; x++ {
+				var sum float64
+				for i, w := range weights {
+					py := clamp(y+i-radius, 0, height-1)
+					sum += rows[py*width+x] * w
+				}
+				blurred[y*width+x] = sum
+			}
+		}
+	})
+	return blurred
+}
+
+/*
+ * Get a function that will threshold any image against the mean of each
+ * pixel's neighborhood minus c, which copes with uneven lighting.
+ */
+func AdaptiveThresholdT(block int, c float64, method string, paletted bool) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		gray := toGray(img)
+		bounds := gray.Bounds()
+
+		var means []float64
+		switch method {
+		case "mean":
+			means = localMeans(gray, block)
+		case "gaussian":
+			means = localGaussianMeans(gray, block)
+		default:
+			return nil, fmt.Errorf("adaptivethreshold: %s is not a valid method", method)
+		}
+
+		width := bounds.Dx()
+		return binaryImage(bounds, paletted, func(x int, y int) bool {
+			i := (y-bounds.Min.Y)*width + x - bounds.Min.X
+			return float64(gray.GrayAt(x, y).Y) > means[i]-c
+		}), nil
+	}
+}
+
+/*
+ * Build a threshold transformation from its command line arguments.
+ */
+func thresholdFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("value", "method", "paletted"); err != nil {
+		return nil, err
+	}
+
+	value, err := args.getInt("value", 128)
+	if err != nil {
+		return nil, err
+	}
+	if value < 0 || value > 255 {
+		return nil, errors.New("value must be between 0 and 255")
+	}
+
+	method := args.getString("method", "fixed")
+	if method != "fixed" && method != "otsu" {
+		return nil, fmt.Errorf("%s is not a valid method", method)
+	}
+
+	paletted, err := args.getBool("paletted", false)
+	if err != nil {
+		return nil, err
+	}
+	return ThresholdT(uint8(value), method == "otsu", paletted), nil
+}
+
+/*
+ * Build an adaptivethreshold transformation from its command line arguments.
+ */
+func adaptiveThresholdFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("block", "c", "method", "paletted"); err != nil {
+		return nil, err
+	}
+
+	block, err := args.getInt("block", 31)
+	if err != nil {
+		return nil, err
+	}
+	if block < 3 || block%2 == 0 {
+		return nil, errors.New("block must be an odd number of at least 3")
+	}
+
+	c, err := args.getFloat("c", 10)
+	if err != nil {
+		return nil, err
+	}
+
+	method := args.getString("method", "mean")
+	if method != "mean" && method != "gaussian" {
+		return nil, fmt.Errorf("%s is not a valid method", method)
+	}
+
+	paletted, err := args.getBool("paletted", false)
+	if err != nil {
+		return nil, err
+	}
+	return AdaptiveThresholdT(block, c, method, paletted), nil
+}
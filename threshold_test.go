@@ -0,0 +1,223 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: threshold_test.go
+ * Description:
+ *   Tests for Otsu's method, summed-area tables and adaptive thresholds.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestOtsuThreshold(t *testing.T) {
+	spikes := func(counts map[int]int) [256]int {
+		var hist [256]int
+		for v, n := range counts {
+			hist[v] = n
+		}
+		return hist
+	}
+	bell := func(centers ...int) [256]int {
+		var hist [256]int
+		for _, c := range centers {
+			for d := -20; d <= 20; d++ {
+				hist[c+d] += int(1000 * math.Exp(-float64(d*d)/50))
+			}
+		}
+		return hist
+	}
+
+	tests := []struct {
+		name   string
+		hist   [256]int
+		lo, hi uint8
+	}{
+		// Every threshold between two spikes splits them equally well, and
+		// the first one is kept.
+		{"two spikes", spikes(map[int]int{50: 100, 200: 100}), 50, 50},
+		{"uneven spikes", spikes(map[int]int{10: 900, 240: 100}), 10, 10},
+		{"two bells", bell(60, 190), 78, 171},
+		{"one value", spikes(map[int]int{100: 10}), 0, 0},
+		{"empty", [256]int{}, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := otsuThreshold(tt.hist); got < tt.lo || got > tt.hi {
+			t.Errorf("%s: otsuThreshold = %d, want %d to %d", tt.name, got, tt.lo, tt.hi)
+		}
+	}
+}
+
+func TestGrayHistogram(t *testing.T) {
+	gray := image.NewGray(image.Rect(3, 2, 13, 9))
+	for i := range gray.Pix {
+		gray.Pix[i] = uint8(i % 7)
+	}
+	hist := grayHistogram(gray)
+	total := 0
+	for v, n := range hist {
+		total += n
+		if v >= 7 && n != 0 {
+			t.Errorf("hist[%d] = %d, want 0", v, n)
+		}
+	}
+	if total != 70 || hist[0] != 10 {
+		t.Errorf("histogram has %d pixels and %d zeros, want 70 and 10", total, hist[0])
+	}
+}
+
+func TestSummedAreaSum(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	bounds := image.Rect(-3, 5, 14, 16)
+	values := make([]float64, bounds.Dx()*bounds.Dy())
+	for i := range values {
+		values[i] = float64(rng.Intn(100))
+	}
+	table := newSummedArea(bounds, values)
+
+	rects := []image.Rectangle{
+		bounds,
+		image.Rect(0, 7, 1, 8),
+		image.Rect(-10, 0, 0, 10),
+		image.Rect(10, 12, 40, 40),
+		image.Rect(20, 20, 30, 30),
+		image.Rect(2, 9, 2, 12),
+	}
+	for i := 0; i < 50; i++ {
+		x, y := bounds.Min.X-5+rng.Intn(bounds.Dx()+10), bounds.Min.Y-5+rng.Intn(bounds.Dy()+10)
+		rects = append(rects, image.Rect(x, y, x+rng.Intn(12), y+rng.Intn(12)))
+	}
+
+	for _, r := range rects {
+		var want float64
+		wantN := 0
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				if image.Pt(x, y).In(r) {
+					want += values[(y-bounds.Min.Y)*bounds.Dx()+x-bounds.Min.X]
+					wantN++
+				}
+			}
+		}
+		if got, n := table.sum(r); got != want || n != wantN {
+			t.Errorf("sum(%v) = %v over %d, want %v over %d", r, got, n, want, wantN)
+		}
+	}
+}
+
+func TestLocalMeans(t *testing.T) {
+	gray := image.NewGray(image.Rect(2, 3, 11, 10))
+	for i := range gray.Pix {
+		gray.Pix[i] = uint8(i * 37 % 256)
+	}
+	bounds := gray.Bounds()
+	for _, block := range []int{3, 5, 31} {
+		means := localMeans(gray, block)
+		radius := block / 2
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				var sum, n float64
+				window := image.Rect(x-radius, y-radius, x+radius+1, y+radius+1).Intersect(bounds)
+				for wy := window.Min.Y; wy < window.Max.Y; wy++ {
+					for wx := window.Min.X; wx < window.Max.X; wx++ {
+						sum += float64(gray.GrayAt(wx, wy).Y)
+						n++
+					}
+				}
+				got := means[(y-bounds.Min.Y)*bounds.Dx()+x-bounds.Min.X]
+				if math.Abs(got-sum/n) > 1e-9 {
+					t.Errorf("block %d: mean at (%d, %d) = %v, want %v", block, x, y, got, sum/n)
+				}
+			}
+		}
+	}
+}
+
+func TestGaussianBlurGrayKeepsFlatImages(t *testing.T) {
+	gray := image.NewGray(image.Rect(0, 0, 9, 6))
+	for i := range gray.Pix {
+		gray.Pix[i] = 77
+	}
+	for _, v := range gaussianBlurGray(gray, 2, 6) {
+		if math.Abs(v-77) > 1e-9 {
+			t.Fatalf("blurring a flat image gave %v, want 77", v)
+		}
+	}
+}
+
+func TestAdaptiveThresholdUnevenLight(t *testing.T) {
+	// Dark text on a background that gets brighter to the right, which no
+	// single threshold can separate.
+	gray := image.NewGray(image.Rect(0, 0, 60, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 60; x++ {
+			v := 40 + 3*x
+			if y == 10 && x%4 == 0 {
+				v -= 40
+			}
+			gray.SetGray(x, y, color.Gray{uint8(v)})
+		}
+	}
+	for _, method := range []string{"mean", "gaussian"} {
+		out, err := AdaptiveThresholdT(11, 10, method, false)(gray)
+		if err != nil {
+			t.Fatal(err)
+		}
+		binary := out.(*image.Gray)
+		for x := 4; x < 56; x++ {
+			want := uint8(255)
+			if x%4 == 0 {
+				want = 0
+			}
+			if got := binary.GrayAt(x, 10).Y; got != want {
+				t.Errorf("%s: pixel %d is %d, want %d", method, x, got, want)
+			}
+			if got := binary.GrayAt(x, 3).Y; got != 255 {
+				t.Errorf("%s: background pixel %d is %d, want 255", method, x, got)
+			}
+		}
+	}
+}
+
+func TestThresholdPaletted(t *testing.T) {
+	gray := image.NewGray(image.Rect(0, 0, 4, 1))
+	copy(gray.Pix, []uint8{0, 128, 129, 255})
+	out, err := ThresholdT(128, false, true)(gray)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paletted := out.(*image.Paletted)
+	for x, want := range []uint8{0, 0, 1, 1} {
+		if got := paletted.ColorIndexAt(x, 0); got != want {
+			t.Errorf("index at %d = %d, want %d", x, got, want)
+		}
+	}
+}
+
+func TestThresholdArgs(t *testing.T) {
+	tests := []struct {
+		commands string
+		ok       bool
+	}{
+		{"threshold", true},
+		{"threshold,value=0,method=otsu,paletted=true", true},
+		{"threshold,value=256", false},
+		{"threshold,method=triangle", false},
+		{"adaptivethreshold", true},
+		{"adaptivethreshold,block=3,c=-5,method=gaussian", true},
+		{"adaptivethreshold,block=4", false},
+		{"adaptivethreshold,block=1", false},
+		{"adaptivethreshold,method=median", false},
+	}
+	for _, tt := range tests {
+		_, err := TokensToTfms(SplitCommands(tt.commands), 1)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.commands, err, tt.ok)
+		}
+	}
+}
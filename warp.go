@@ -0,0 +1,361 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: warp.go
+ * Description:
+ *   Geometric distortions built on inverse mapping: swirl, wave, fisheye,
+ *   barrel lens correction, and polar and cartesian coordinates.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+/*
+ * Map a point of the output image to the point of the source image that
+ * it shows. Points are continuous, so the center of pixel (x, y) is at
+ * (x + 0.5, y + 0.5).
+ *
+ * Returns: The source point, and false if the output point shows nothing.
+ */
+type warpMapping func(x float64, y float64) (float64, float64, bool)
+
+/*
+ * Build an image covering bounds by looking up every output pixel in the
+ * source image with mapping and sampling it with filter. Output rows are
+ * split between workers. Pixels that map outside of the source image are
+ * filled with background.
+ */
+func warpImage(img image.Image, bounds image.Rectangle, mapping warpMapping, filter resampleFilter, background color.NRGBA) *image.RGBA {
+	src := toRGBA(img)
+	srcBounds := src.Bounds()
+	dst := image.NewRGBA(bounds)
+	fill := color.RGBAModel.Convert(background).(color.RGBA)
+
+	minX, maxX := float64(srcBounds.Min.X), float64(srcBounds.Max.X)
+	minY, maxY := float64(srcBounds.Min.Y), float64(srcBounds.Max.Y)
+
+	pool := GetGlobalWorkers()
+	pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				sx, sy, ok := mapping(float64(x)+0.5, float64(y)+0.5)
+				clr := fill
+				if ok && sx >= minX && sx <= maxX && sy >= minY && sy <= maxY {
+					// Sample between pixel centers, staying inside the image
+					// so that the edges don't fade out.
+					clr = filter(
+						src,
+						math.Min(maxX-1, math.Max(minX, sx-0.5)),
+						math.Min(maxY-1, math.Max(minY, sy-0.5)),
+					)
+				}
+
+				i := dst.PixOffset(x, y)
+				dst.Pix[i] = clr.R
+				dst.Pix[i+1] = clr.G
+				dst.Pix[i+2] = clr.B
+				dst.Pix[i+3] = clr.A
+			}
+		}
+	})
+	return dst
+}
+
+/*
+ * Get the continuous center point of a rectangle.
+ */
+func centerOf(bounds image.Rectangle) (float64, float64) {
+	return float64(bounds.Min.X+bounds.Max.X) / 2, float64(bounds.Min.Y+bounds.Max.Y) / 2
+}
+
+/*
+ * Get a function that will twist any image around its center. Points at
+ * the center turn by angle degrees, and the twist fades out towards
+ * radius, which is a fraction of half of the shorter side.
+ */
+func SwirlT(angle float64, radius float64, background color.NRGBA) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		bounds := img.Bounds()
+		cx, cy := centerOf(bounds)
+		reach := radius * float64(min(bounds.Dx(), bounds.Dy())) / 2
+		rad := angle * math.Pi / 180
+
+		mapping := func(x float64, y float64) (float64, float64, bool) {
+			dx, dy := x-cx, y-cy
+			d := math.Hypot(dx, dy)
+			if d >= reach {
+				return x, y, true
+			}
+			falloff := 1 - d/reach
+			sin, cos := math.Sincos(rad * falloff * falloff)
+			return cx + dx*cos - dy*sin, cy + dx*sin + dy*cos, true
+		}
+		return warpImage(img, bounds, mapping, sampleBilinear, background), nil
+	}
+}
+
+/*
+ * Get a function that will make any image ripple like a flag. Rows move
+ * sideways for horizontal waves, columns move up and down for vertical
+ * waves, and both move for both.
+ */
+func WaveT(amplitude float64, wavelength float64, direction string, background color.NRGBA) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		horizontal := direction == "horizontal" || direction == "both"
+		vertical := direction == "vertical" || direction == "both"
+		k := 2 * math.Pi / wavelength
+
+		mapping := func(x float64, y float64) (float64, float64, bool) {
+			sx, sy := x, y
+			if horizontal {
+				sx += amplitude * math.Sin(y*k)
+			}
+			if vertical {
+				sy += amplitude * math.Sin(x*k)
+			}
+			return sx, sy, true
+		}
+		return warpImage(img, img.Bounds(), mapping, sampleBilinear, background), nil
+	}
+}
+
+/*
+ * Get a function that will bulge the middle of any image like a fisheye
+ * lens. Distances are measured as a fraction of half of the shorter side,
+ * and points inside that circle are looked up at distance^(1 + strength),
+ * so positive strengths magnify the center and negative ones pinch it.
+ */
+func FisheyeT(strength float64, background color.NRGBA) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		bounds := img.Bounds()
+		cx, cy := centerOf(bounds)
+		reach := float64(min(bounds.Dx(), bounds.Dy())) / 2
+
+		mapping := func(x float64, y float64) (float64, float64, bool) {
+			dx, dy := x-cx, y-cy
+			d := math.Hypot(dx, dy) / reach
+			if d >= 1 || d == 0 {
+				return x, y, true
+			}
+			scale := math.Pow(d, 1+strength) / d
+			return cx + dx*scale, cy + dy*scale, true
+		}
+		return warpImage(img, bounds, mapping, sampleBilinear, background), nil
+	}
+}
+
+/*
+ * Get a function that will apply radial lens distortion to any image with
+ * the Brown model: a point at distance r from the center, measured as a
+ * fraction of half of the diagonal, is looked up at r * (1 + k1 r^2 +
+ * k2 r^4). Negative coefficients correct barrel distortion and positive
+ * ones correct pincushion distortion.
+ */
+func BarrelT(k1 float64, k2 float64, background color.NRGBA) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		bounds := img.Bounds()
+		cx, cy := centerOf(bounds)
+		halfDiag := math.Hypot(float64(bounds.Dx()), float64(bounds.Dy())) / 2
+
+		mapping := func(x float64, y float64) (float64, float64, bool) {
+			dx, dy := x-cx, y-cy
+			r2 := (dx*dx + dy*dy) / (halfDiag * halfDiag)
+			scale := 1 + k1*r2 + k2*r2*r2
+			return cx + dx*scale, cy + dy*scale, true
+		}
+		return warpImage(img, bounds, mapping, sampleBilinear, background), nil
+	}
+}
+
+/*
+ * Get a function that will unroll any image into polar coordinates: the
+ * output x axis goes once around the center, starting to the right and
+ * turning clockwise, and the y axis goes from the center out to the
+ * corners. cartesian undoes it.
+ */
+func PolarT(background color.NRGBA) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		bounds := img.Bounds()
+		cx, cy := centerOf(bounds)
+		width, height := float64(bounds.Dx()), float64(bounds.Dy())
+		halfDiag := math.Hypot(width, height) / 2
+
+		mapping := func(x float64, y float64) (float64, float64, bool) {
+			theta := (x - float64(bounds.Min.X)) / width * 2 * math.Pi
+			r := (y - float64(bounds.Min.Y)) / height * halfDiag
+			sin, cos := math.Sincos(theta)
+			return cx + r*cos, cy + r*sin, true
+		}
+		return warpImage(img, bounds, mapping, sampleBilinear, background), nil
+	}
+}
+
+/*
+ * Get a function that will roll an image in polar coordinates, like the
+ * output of polar, back up around the center.
+ */
+func CartesianT(background color.NRGBA) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		bounds := img.Bounds()
+		cx, cy := centerOf(bounds)
+		width, height := float64(bounds.Dx()), float64(bounds.Dy())
+		halfDiag := math.Hypot(width, height) / 2
+
+		mapping := func(x float64, y float64) (float64, float64, bool) {
+			dx, dy := x-cx, y-cy
+			theta := math.Atan2(dy, dx)
+			if theta < 0 {
+				theta += 2 * math.Pi
+			}
+			sx := float64(bounds.Min.X) + theta/(2*math.Pi)*width
+			sy := float64(bounds.Min.Y) + math.Hypot(dx, dy)/halfDiag*height
+			return sx, sy, true
+		}
+		return warpImage(img, bounds, mapping, sampleBilinear, background), nil
+	}
+}
+
+/*
+ * Read the background color shared by every warp.
+ */
+func warpBackground(args tfmArgs) (color.NRGBA, error) {
+	return parseColor(args.getString("background", "transparent"))
+}
+
+/*
+ * Build a swirl transformation from its command line arguments.
+ */
+func swirlFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("angle", "radius", "background"); err != nil {
+		return nil, err
+	}
+
+	angle, err := args.getFloat("angle", 180)
+	if err != nil {
+		return nil, err
+	}
+	radius, err := args.getFloat("radius", 1)
+	if err != nil {
+		return nil, err
+	}
+	background, err := warpBackground(args)
+	if err != nil {
+		return nil, err
+	}
+	if radius <= 0 {
+		return nil, errors.New("radius must be greater than 0")
+	}
+	return SwirlT(angle, radius, background), nil
+}
+
+/*
+ * Build a wave transformation from its command line arguments.
+ */
+func waveFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("amplitude", "wavelength", "direction", "background"); err != nil {
+		return nil, err
+	}
+
+	amplitude, err := args.getFloat("amplitude", 10)
+	if err != nil {
+		return nil, err
+	}
+	wavelength, err := args.getFloat("wavelength", 60)
+	if err != nil {
+		return nil, err
+	}
+	background, err := warpBackground(args)
+	if err != nil {
+		return nil, err
+	}
+	if wavelength <= 0 {
+		return nil, errors.New("wavelength must be greater than 0")
+	}
+
+	direction := args.getString("direction", "horizontal")
+	if direction != "horizontal" && direction != "vertical" && direction != "both" {
+		return nil, fmt.Errorf("%s is not a valid direction", direction)
+	}
+	return WaveT(amplitude, wavelength, direction, background), nil
+}
+
+/*
+ * Build a fisheye transformation from its command line arguments.
+ */
+func fisheyeFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("strength", "background"); err != nil {
+		return nil, err
+	}
+
+	strength, err := args.getFloat("strength", 0.5)
+	if err != nil {
+		return nil, err
+	}
+	background, err := warpBackground(args)
+	if err != nil {
+		return nil, err
+	}
+	if strength <= -1 {
+		return nil, errors.New("strength must be greater than -1")
+	}
+	return FisheyeT(strength, background), nil
+}
+
+/*
+ * Build a barrel transformation from its command line arguments.
+ */
+func barrelFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("k1", "k2", "background"); err != nil {
+		return nil, err
+	}
+
+	k1, err := args.getFloat("k1", -0.2)
+	if err != nil {
+		return nil, err
+	}
+	k2, err := args.getFloat("k2", 0)
+	if err != nil {
+		return nil, err
+	}
+	background, err := warpBackground(args)
+	if err != nil {
+		return nil, err
+	}
+	return BarrelT(k1, k2, background), nil
+}
+
+/*
+ * Build a polar transformation from its command line arguments.
+ */
+func polarFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("background"); err != nil {
+		return nil, err
+	}
+
+	background, err := warpBackground(args)
+	if err != nil {
+		return nil, err
+	}
+	return PolarT(background), nil
+}
+
+/*
+ * Build a cartesian transformation from its command line arguments.
+ */
+func cartesianFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("background"); err != nil {
+		return nil, err
+	}
+
+	background, err := warpBackground(args)
+	if err != nil {
+		return nil, err
+	}
+	return CartesianT(background), nil
+}
@@ -0,0 +1,206 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: warp_test.go
+ * Description:
+ *   Tests for the geometric distortions.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestWarpsWithNoEffectKeepTheImage(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewRGBA(image.Rect(3, -4, 24, 13))
+	rng.Read(img.Pix)
+	clear := color.NRGBA{}
+
+	warps := map[string]func(image.Image) (image.Image, error){
+		"swirl":   SwirlT(0, 1, clear),
+		"wave":    WaveT(0, 20, "both", clear),
+		"fisheye": FisheyeT(0, clear),
+		"barrel":  BarrelT(0, 0, clear),
+	}
+	for name, warp := range warps {
+		out, err := warp(img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst := out.(*image.RGBA)
+		if dst.Bounds() != img.Bounds() || string(dst.Pix) != string(img.Pix) {
+			t.Errorf("%s with no effect changed the image", name)
+		}
+	}
+}
+
+func TestSwirlOnlyTurnsInsideRadius(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	img := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	rng.Read(img.Pix)
+	out, err := SwirlT(120, 0.5, color.NRGBA{})(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := out.(*image.RGBA)
+	changed := 0
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 40; x++ {
+			d := math.Hypot(float64(x)+0.5-20, float64(y)+0.5-20)
+			same := dst.RGBAAt(x, y) == img.RGBAAt(x, y)
+			if d >= 10 && !same {
+				t.Errorf("pixel at (%d, %d) is outside the swirl but changed", x, y)
+			}
+			if !same {
+				changed++
+			}
+		}
+	}
+	if changed == 0 {
+		t.Error("the swirl did not change anything")
+	}
+}
+
+func TestWaveMovesAlongItsDirection(t *testing.T) {
+	// Every column is one color, so moving pixels up and down only shows
+	// at the top and bottom, where the background comes in.
+	img := image.NewRGBA(image.Rect(0, 0, 30, 30))
+	for x := 0; x < 30; x++ {
+		for y := 0; y < 30; y++ {
+			img.SetRGBA(x, y, color.RGBA{uint8(8 * x), 0, 0, 255})
+		}
+	}
+	out, err := WaveT(3, 10, "vertical", color.NRGBA{})(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := out.(*image.RGBA)
+	for y := 4; y < 26; y++ {
+		for x := 0; x < 30; x++ {
+			if dst.RGBAAt(x, y) != img.RGBAAt(x, y) {
+				t.Fatalf("a vertical wave changed (%d, %d) in a column of one color", x, y)
+			}
+		}
+	}
+
+	out, err = WaveT(3, 10, "horizontal", color.NRGBA{})(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out.(*image.RGBA).Pix) == string(img.Pix) {
+		t.Error("a horizontal wave did not move the columns")
+	}
+}
+
+func TestBarrelFillsBackground(t *testing.T) {
+	img := flatImage(20, 20, color.White)
+	background := color.NRGBA{0, 0, 255, 255}
+	out, err := BarrelT(1, 0, background)(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := out.(*image.RGBA)
+	if got := dst.RGBAAt(0, 0); got != (color.RGBA{0, 0, 255, 255}) {
+		t.Errorf("the corner is %v, want the background", got)
+	}
+	if got := dst.RGBAAt(10, 10); got != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("the center is %v, want the image", got)
+	}
+}
+
+func TestFisheyeMagnifiesCenter(t *testing.T) {
+	// A small dot in the middle grows under a fisheye and shrinks under
+	// a pinch.
+	count := func(strength float64) int {
+		img := squareImage(image.Pt(41, 41), image.Rect(18, 18, 23, 23))
+		out, err := FisheyeT(strength, color.NRGBA{})(img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for y := 0; y < 41; y++ {
+			for x := 0; x < 41; x++ {
+				if out.(*image.RGBA).RGBAAt(x, y).R > 128 {
+					n++
+				}
+			}
+		}
+		return n
+	}
+	if none, bulge, pinch := count(0), count(0.5), count(-0.5); !(bulge > none && none > pinch) {
+		t.Errorf("the dot covers %d pixels bulged, %d as it is and %d pinched", bulge, none, pinch)
+	}
+}
+
+func TestPolarAndCartesian(t *testing.T) {
+	// Rings around the center become rows in polar coordinates.
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			d := math.Hypot(float64(x)+0.5-32, float64(y)+0.5-32)
+			img.SetRGBA(x, y, color.RGBA{uint8(5 * d), 0, 0, 255})
+		}
+	}
+	out, err := PolarT(color.NRGBA{})(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	polar := out.(*image.RGBA)
+	for y := 0; y < 40; y++ {
+		lo, hi := uint8(255), uint8(0)
+		for x := 0; x < 64; x++ {
+			v := polar.RGBAAt(x, y).R
+			lo, hi = min(lo, v), max(hi, v)
+		}
+		if hi-lo > 6 {
+			t.Errorf("polar row %d goes from %d to %d, want about one value", y, lo, hi)
+		}
+	}
+
+	// And rolling the rows back up gives the rings again.
+	out, err = CartesianT(color.NRGBA{})(polar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back := out.(*image.RGBA)
+	for y := 8; y < 56; y++ {
+		for x := 8; x < 56; x++ {
+			got, want := int(back.RGBAAt(x, y).R), int(img.RGBAAt(x, y).R)
+			if got < want-8 || got > want+8 {
+				t.Fatalf("after polar and cartesian, (%d, %d) is %d, want about %d", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestWarpArgs(t *testing.T) {
+	tests := []struct {
+		commands string
+		ok       bool
+	}{
+		{"swirl", true},
+		{"swirl,angle=-90,radius=0.5,background=#ffffff", true},
+		{"swirl,radius=0", false},
+		{"wave,amplitude=5,wavelength=30,direction=both", true},
+		{"wave,wavelength=0", false},
+		{"wave,direction=diagonal", false},
+		{"fisheye,strength=-0.5", true},
+		{"fisheye,strength=-1", false},
+		{"barrel,k1=0.1,k2=0.05", true},
+		{"barrel,background=nope", false},
+		{"polar", true},
+		{"cartesian,background=#000000", true},
+		{"polar,angle=1", false},
+	}
+	for _, tt := range tests {
+		_, err := TokensToTfms(SplitCommands(tt.commands), 1)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.commands, err, tt.ok)
+		}
+	}
+}
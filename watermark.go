@@ -0,0 +1,178 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: watermark.go
+ * Description:
+ *   Draw a logo over an image, either once in a corner or tiled diagonally
+ *   across all of it. The logo is scaled to the image so that the result
+ *   looks the same at any resolution.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+/*
+ * How a watermark is drawn:
+ *   scale: the width of the logo as a fraction of the width of the image
+ *   opacity: how opaque the logo is, from 0 to 1
+ *   angle: how many degrees the logo is turned
+ *   gravity, margin: where a single logo goes, and how far it stays from
+ *     the edges in pixels
+ *   tile, gap: whether the logo is repeated over the whole image, every
+ *     row shifted by half a logo, and the space between the logos in pixels
+ */
+type watermarkOptions struct {
+	scale   float64
+	opacity float64
+	angle   float64
+	gravity string
+	margin  int
+	tile    bool
+	gap     int
+}
+
+// How a watermark is drawn when nothing else is asked for.
+var DEFAULT_WATERMARK = watermarkOptions{0.15, 0.5, 0, "southeast", 16, false, 16}
+
+// How many degrees tiled logos are turned when no angle is given, so that
+// they run up and to the right.
+const DEFAULT_TILE_ANGLE = -30
+
+/*
+ * Get the top left corners of the logos of a tiled watermark, on a grid
+ * centered on the image and covering all of it.
+ */
+func tileCorners(bounds image.Rectangle, size image.Point, gap int) []image.Point {
+	stepX, stepY := size.X+gap, size.Y+gap
+	cx, cy := (bounds.Min.X+bounds.Max.X)/2, (bounds.Min.Y+bounds.Max.Y)/2
+	firstRow := int(math.Floor(float64(bounds.Min.Y-cy-size.Y)/float64(stepY))) + 1
+	lastRow := int(math.Ceil(float64(bounds.Max.Y-cy) / float64(stepY)))
+
+	corners := make([]image.Point, 0)
+	for row := firstRow; row < lastRow; row++ {
+		shift := 0
+		if row%2 != 0 {
+			shift = stepX / 2
+		}
+		firstCol := int(math.Floor(float64(bounds.Min.X-cx-shift-size.X)/float64(stepX))) + 1
+		lastCol := int(math.Ceil(float64(bounds.Max.X-cx-shift) / float64(stepX)))
+		for col := firstCol; col < lastCol; col++ {
+			corners = append(corners, image.Pt(cx+shift+col*stepX, cy+row*stepY))
+		}
+	}
+	return corners
+}
+
+/*
+ * Get a function that will draw a logo over any image.
+ */
+func WatermarkT(logo image.Image, opts watermarkOptions) func(image.Image) (image.Image, error) {
+	return func(img image.Image) (image.Image, error) {
+		bounds := img.Bounds()
+		newImg := copyRGBA(img)
+
+		scale := opts.scale * float64(bounds.Dx()) / float64(logo.Bounds().Dx())
+		mark, _ := transformSticker(logo, scale, opts.angle, false, [2]float64{0.5, 0.5})
+		size := mark.Bounds().Size()
+
+		var corners []image.Point
+		if opts.tile {
+			corners = tileCorners(bounds, size, opts.gap)
+		} else {
+			inner := bounds.Inset(opts.margin)
+			if inner.Empty() {
+				return nil, errors.New("the margin leaves no room for the watermark")
+			}
+			offset, err := gravityOffset(opts.gravity, inner.Size(), size)
+			if err != nil {
+				return nil, err
+			}
+			corners = []image.Point{inner.Min.Add(offset)}
+		}
+
+		// Every worker draws the logos over its own rows of the image.
+		opacity := image.NewUniform(color.Alpha{uint8(math.Round(opts.opacity * 255))})
+		pool := GetGlobalWorkers()
+		pool.ParallelFor(bounds.Min.Y, bounds.Max.Y, func(lo int, hi int) {
+			rows := image.Rect(bounds.Min.X, lo, bounds.Max.X, hi)
+			for _, corner := range corners {
+				area := mark.Bounds().Add(corner).Intersect(rows)
+				if area.Empty() {
+					continue
+				}
+				from := area.Min.Sub(corner)
+				draw.DrawMask(newImg, area, mark, from, opacity, image.Point{}, draw.Over)
+			}
+		})
+
+		return newImg, nil
+	}
+}
+
+/*
+ * Build a watermark transformation from its command line arguments.
+ */
+func watermarkFromArgs(args tfmArgs) (func(image.Image) (image.Image, error), error) {
+	if err := args.allow("file", "gravity", "margin", "opacity", "scale", "rotate", "tile", "gap"); err != nil {
+		return nil, err
+	}
+	if !args.has("file") {
+		return nil, errors.New("a logo file must be given")
+	}
+
+	opts := DEFAULT_WATERMARK
+	var err error
+	opts.gravity = args.getString("gravity", opts.gravity)
+	if _, prs := GRAVITIES[opts.gravity]; !prs {
+		return nil, fmt.Errorf("%s is not a valid gravity", opts.gravity)
+	}
+	opts.margin, err = args.getInt("margin", opts.margin)
+	if err != nil {
+		return nil, err
+	}
+	opts.opacity, err = args.getFloat("opacity", opts.opacity)
+	if err != nil {
+		return nil, err
+	}
+	opts.scale, err = args.getFloat("scale", opts.scale)
+	if err != nil {
+		return nil, err
+	}
+	opts.tile, err = args.getBool("tile", opts.tile)
+	if err != nil {
+		return nil, err
+	}
+	if opts.tile {
+		opts.angle = DEFAULT_TILE_ANGLE
+	}
+	opts.angle, err = args.getFloat("rotate", opts.angle)
+	if err != nil {
+		return nil, err
+	}
+	opts.gap, err = args.getInt("gap", opts.gap)
+	if err != nil {
+		return nil, err
+	}
+	if opts.margin < 0 || opts.gap < 0 {
+		return nil, errors.New("margin and gap must not be negative")
+	}
+	if opts.opacity < 0 || opts.opacity > 1 {
+		return nil, errors.New("opacity must be between 0 and 1")
+	}
+	if opts.scale <= 0 {
+		return nil, errors.New("scale must be greater than 0")
+	}
+
+	logo, err := decodeImage(args.getString("file", ""))
+	if err != nil {
+		return nil, err
+	}
+	return WatermarkT(logo, opts), nil
+}
@@ -0,0 +1,164 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: watermark_test.go
+ * Description:
+ *   Tests for drawing logos over images.
+ */
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTileCornersCoverImage(t *testing.T) {
+	tests := []struct {
+		bounds image.Rectangle
+		size   image.Point
+	}{
+		{image.Rect(0, 0, 100, 60), image.Pt(10, 10)},
+		{image.Rect(-13, 7, 41, 90), image.Pt(7, 12)},
+		{image.Rect(0, 0, 5, 5), image.Pt(20, 20)},
+	}
+	for _, tt := range tests {
+		corners := tileCorners(tt.bounds, tt.size, 0)
+		covered := make(map[image.Point]int)
+		for _, corner := range corners {
+			tile := image.Rectangle{corner, corner.Add(tt.size)}
+			if !tile.Overlaps(tt.bounds) {
+				t.Errorf("%v: the tile at %v is outside the image", tt.bounds, corner)
+			}
+			for y := tile.Min.Y; y < tile.Max.Y; y++ {
+				for x := tile.Min.X; x < tile.Max.X; x++ {
+					covered[image.Pt(x, y)]++
+				}
+			}
+		}
+		for y := tt.bounds.Min.Y; y < tt.bounds.Max.Y; y++ {
+			for x := tt.bounds.Min.X; x < tt.bounds.Max.X; x++ {
+				if n := covered[image.Pt(x, y)]; n != 1 {
+					t.Fatalf("%v: (%d, %d) is covered by %d tiles, want 1", tt.bounds, x, y, n)
+				}
+			}
+		}
+	}
+}
+
+func TestTileCornersGap(t *testing.T) {
+	// One tile sits at the center, and the next row is shifted by half a step.
+	corners := tileCorners(image.Rect(0, 0, 100, 100), image.Pt(10, 10), 6)
+	has := func(p image.Point) bool {
+		for _, corner := range corners {
+			if corner == p {
+				return true
+			}
+		}
+		return false
+	}
+	for _, p := range []image.Point{{50, 50}, {66, 50}, {34, 50}, {58, 66}, {42, 34}} {
+		if !has(p) {
+			t.Errorf("no tile at %v", p)
+		}
+	}
+	if has(image.Pt(50, 66)) {
+		t.Error("the row below the center is not shifted")
+	}
+}
+
+func TestWatermarkPosition(t *testing.T) {
+	img := flatImage(100, 50, color.Black)
+	logo := flatImage(20, 20, color.White)
+	tests := []struct {
+		gravity string
+		at      image.Point
+	}{
+		{"southeast", image.Pt(74, 24)},
+		{"northwest", image.Pt(16, 16)},
+		{"center", image.Pt(45, 20)},
+	}
+	for _, tt := range tests {
+		opts := DEFAULT_WATERMARK
+		opts.scale, opts.opacity, opts.gravity = 0.1, 1, tt.gravity
+		out, err := WatermarkT(logo, opts)(img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rgba := out.(*image.RGBA)
+		logoArea := image.Rectangle{tt.at, tt.at.Add(image.Pt(10, 10))}
+		for y := 0; y < 50; y++ {
+			for x := 0; x < 100; x++ {
+				want := uint8(0)
+				if image.Pt(x, y).In(logoArea) {
+					want = 255
+				}
+				if got := rgba.RGBAAt(x, y).R; got != want {
+					t.Fatalf("%s: (%d, %d) is %d, want %d", tt.gravity, x, y, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestWatermarkOpacity(t *testing.T) {
+	opts := DEFAULT_WATERMARK
+	opts.scale, opts.opacity, opts.tile, opts.gap = 0.5, 0.5, true, 0
+	out, err := WatermarkT(flatImage(4, 4, color.White), opts)(flatImage(40, 30, color.Black))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Tiles with no gap and no turn cover the image at half opacity.
+	for i, v := range out.(*image.RGBA).Pix {
+		if i%4 != 3 && (v < 127 || v > 128) {
+			t.Fatalf("value %d at %d, want half of white", v, i)
+		}
+	}
+}
+
+func TestWatermarkMarginTooLarge(t *testing.T) {
+	opts := DEFAULT_WATERMARK
+	opts.margin = 25
+	logo := flatImage(4, 4, color.White)
+	if _, err := WatermarkT(logo, opts)(flatImage(100, 50, color.Black)); err == nil {
+		t.Error("a margin that covers the image should be an error")
+	}
+}
+
+func TestWatermarkArgs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logo.png")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(file, flatImage(4, 4, color.White)); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	tests := []struct {
+		commands string
+		ok       bool
+	}{
+		{"watermark,file=" + path, true},
+		{"watermark,file=" + path + ",gravity=north,margin=0,opacity=1,scale=0.5,rotate=10", true},
+		{"watermark,file=" + path + ",tile=true,gap=0", true},
+		{"watermark", false},
+		{"watermark,file=/does/not/exist.png", false},
+		{"watermark,file=" + path + ",gravity=up", false},
+		{"watermark,file=" + path + ",margin=-1", false},
+		{"watermark,file=" + path + ",gap=-1", false},
+		{"watermark,file=" + path + ",opacity=1.5", false},
+		{"watermark,file=" + path + ",scale=0", false},
+		{"watermark,file=" + path + ",size=3", false},
+	}
+	for _, tt := range tests {
+		_, err := TokensToTfms(SplitCommands(tt.commands), 1)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.commands, err, tt.ok)
+		}
+	}
+}
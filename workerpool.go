@@ -74,3 +74,38 @@ func (p *WorkerPool) WaitAndStop() {
     close(p.tasksCh)
     p.wg.Wait()
 }
+
+/*
+ * Split the range [start, end) into one chunk per worker and run fn on
+ * each chunk in the pool. This will wait until every chunk is done, so it
+ * must not be called from inside a task that is running in the pool.
+ */
+func (p *WorkerPool) ParallelFor(start int, end int, fn func(lo int, hi int)) {
+    total := end - start
+    if total <= 0 {
+        return
+    }
+
+    numChunks := p.NumWorkers
+    if numChunks > total {
+        numChunks = total
+    }
+    chunk := total / numChunks
+
+    var wg sync.WaitGroup
+    wg.Add(numChunks)
+
+    for i := 0; i < numChunks; i++ {
+        lo := start + i*chunk
+        hi := lo + chunk
+        if i == numChunks-1 {
+            // The last chunk gets the remainder.
+            hi = end
+        }
+        p.Submit(func() {
+            defer wg.Done()
+            fn(lo, hi)
+        })
+    }
+    wg.Wait()
+}
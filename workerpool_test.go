@@ -0,0 +1,52 @@
+/*
+ * Authors: Dhruv Patel and Ayush Sharma
+ * File: workerpool_test.go
+ * Description:
+ *   Tests for the worker pool, and the pool that every other test runs on.
+ */
+
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// How many workers the tests run with, so that work is really split up.
+const TEST_WORKERS = 4
+
+func TestMain(m *testing.M) {
+	pool = WpNew(TEST_WORKERS, TEST_WORKERS)
+	pool.Start()
+	code := m.Run()
+	pool.WaitAndStop()
+	os.Exit(code)
+}
+
+func TestParallelForCoversRangeOnce(t *testing.T) {
+	tests := []struct {
+		start, end int
+	}{
+		{0, 0},
+		{5, 2},
+		{0, 1},
+		{0, 3},
+		{0, TEST_WORKERS},
+		{7, 30},
+		{-10, 91},
+	}
+	for _, tt := range tests {
+		total := max(0, tt.end-tt.start)
+		hits := make([]int, total)
+		pool.ParallelFor(tt.start, tt.end, func(lo int, hi int) {
+			for i := lo; i < hi; i++ {
+				hits[i-tt.start]++
+			}
+		})
+		for i, n := range hits {
+			if n != 1 {
+				t.Errorf("ParallelFor(%d, %d) ran %d %d times, want 1", tt.start, tt.end, tt.start+i, n)
+			}
+		}
+	}
+}